@@ -2,12 +2,13 @@ module github.com/lassik/massikone
 
 require (
 	github.com/Masterminds/squirrel v0.0.0-20181030160206-3ba160b0147f
+	github.com/boombuler/barcode v1.0.0
 	github.com/disintegration/imaging v1.5.0
 	github.com/gorilla/handlers v1.4.0
 	github.com/gorilla/mux v1.6.2
 	github.com/gorilla/sessions v1.1.3
 	github.com/hoisie/mustache v0.0.0-20160804235033-6375acf62c69
-	github.com/jung-kurt/gofpdf v1.0.0
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/lann/builder v0.0.0-20180802200727-47ae307949d0 // indirect
 	github.com/lann/ps v0.0.0-20150810152359-62de8c46ede0 // indirect
 	github.com/lassik/airfreight v0.0.0-20181129000355-87a12f79a206
@@ -16,6 +17,6 @@ require (
 	github.com/subosito/gotenv v1.1.1
 	github.com/toqueteos/webbrowser v1.1.0
 	github.com/xo/dburl v0.0.0-20180921222126-e33971d4c132
-	golang.org/x/image v0.0.0-20181109232246-249dc8530c0e // indirect
+	golang.org/x/image v0.0.0-20190910094157-69e4b8554b2a // indirect
 	golang.org/x/text v0.3.0
 )
@@ -12,8 +12,10 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -22,6 +24,7 @@ import (
 	"github.com/lassik/airfreight"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
+	"github.com/markbates/goth/providers/github"
 	"github.com/markbates/goth/providers/google"
 	"github.com/subosito/gotenv"
 	"github.com/toqueteos/webbrowser"
@@ -34,6 +37,7 @@ const iniFileName = "massikone.ini"
 const logFileName = "massikone.log"
 const sessionName = "massikone"
 const sessionCurrentUser = "current_user"
+const sessionLinkLogin = "link_login"
 
 var cookieStore *sessions.CookieStore
 var publicURL string
@@ -57,6 +61,13 @@ var settingsTemplate = getTemplate("/settings.mustache")
 var aboutTemplate = getTemplate("/about.mustache")
 var compareTemplate = getTemplate("/compare.mustache")
 var loginTemplate = getTemplate("/login.mustache")
+var userTemplate = getTemplate("/user.mustache")
+var invoicesTemplate = getTemplate("/invoices.mustache")
+var invoiceTemplate = getTemplate("/invoice.mustache")
+var membersTemplate = getTemplate("/members.mustache")
+var memberTemplate = getTemplate("/member.mustache")
+var budgetTemplate = getTemplate("/budget.mustache")
+var reimbursementsTemplate = getTemplate("/reimbursements.mustache")
 
 func check(err error) {
 	if err != nil {
@@ -114,6 +125,16 @@ func withModel(h ModelHandlerFunc, adminOnly bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		m := model.MakeModel(getSessionUserID(r), adminOnly)
 		defer m.Close()
+		if m.Err == model.ErrPendingUser {
+			m.Err = nil
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				http.Error(w, http.StatusText(http.StatusForbidden),
+					http.StatusForbidden)
+				return
+			}
+			getPendingPage(w, r)
+			return
+		}
 		if m.Err != nil {
 			log.Print(m.Err)
 			http.Error(w, http.StatusText(http.StatusUnauthorized),
@@ -138,14 +159,104 @@ func adminOnly(h ModelHandlerFunc) http.HandlerFunc {
 	return withModel(h, true)
 }
 
+// isVerifiedEmail asks the login provider whether the user has proved
+// to own the email address. Providers we cannot ask are not trusted.
+func isVerifiedEmail(gothUser goth.User) bool {
+	var url string
+	switch gothUser.Provider {
+	case "google":
+		url = "https://www.googleapis.com/oauth2/v2/userinfo"
+	case "github":
+		url = "https://api.github.com/user/emails"
+	default:
+		return false
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	req.Header.Set("Authorization", "Bearer "+gothUser.AccessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Email verification: %s", resp.Status)
+		return false
+	}
+	if gothUser.Provider == "github" {
+		var emails []struct {
+			Email    string `json:"email"`
+			Verified bool   `json:"verified"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
+			log.Print(err)
+			return false
+		}
+		for _, e := range emails {
+			if e.Verified && strings.EqualFold(e.Email, gothUser.Email) {
+				return true
+			}
+		}
+		return false
+	}
+	var info struct {
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		log.Print(err)
+		return false
+	}
+	return info.VerifiedEmail && strings.EqualFold(info.Email, gothUser.Email)
+}
+
+// isAutoApprovedEmail tells whether the user has a verified email
+// address whose domain is listed in AUTO_APPROVE_EMAIL_DOMAINS
+// (comma-separated).
+func isAutoApprovedEmail(gothUser goth.User) bool {
+	email := gothUser.Email
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range strings.Split(
+		os.Getenv("AUTO_APPROVE_EMAIL_DOMAINS"), ",") {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed != "" && allowed == domain {
+			return isVerifiedEmail(gothUser)
+		}
+	}
+	return false
+}
+
 func finishLogin(w http.ResponseWriter, r *http.Request) {
 	gothUser, err := gothic.CompleteUserAuth(w, r)
 	if err != nil {
 		log.Print(err)
 		return
 	}
+	session, _ := cookieStore.Get(r, sessionName)
+	if _, ok := session.Values[sessionLinkLogin]; ok {
+		delete(session.Values, sessionLinkLogin)
+		session.Save(r, w)
+		if userID := getSessionUserID(r); userID > 0 {
+			err = model.LinkUserAuth(userID,
+				gothUser.Provider, gothUser.UserID)
+			if err != nil {
+				log.Print(err)
+			}
+			http.Redirect(w, r, "/kayttaja", http.StatusSeeOther)
+			return
+		}
+	}
 	userID, err := model.GetOrPutUser(
-		gothUser.Provider, gothUser.UserID, gothUser.Name)
+		gothUser.Provider, gothUser.UserID, gothUser.Name,
+		isAutoApprovedEmail(gothUser))
 	if err != nil {
 		log.Print(err)
 		return
@@ -154,6 +265,17 @@ func finishLogin(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+func beginLinkLogin(w http.ResponseWriter, r *http.Request) {
+	if getSessionUserID(r) <= 0 {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	session, _ := cookieStore.Get(r, sessionName)
+	session.Values[sessionLinkLogin] = "1"
+	session.Save(r, w)
+	gothic.BeginAuthHandler(w, r)
+}
+
 func logout(w http.ResponseWriter, r *http.Request) {
 	if publicURL != "" {
 		setSessionUserID(w, r, 0)
@@ -162,23 +284,69 @@ func logout(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// providerTitles are the names of the login providers shown to users.
+var providerTitles = map[string]string{
+	"github": "GitHub",
+	"google": "Google",
+}
+
+// loginProviders lists the login providers in use in alphabetical
+// order.
+func loginProviders() []map[string]interface{} {
+	var names []string
+	for name := range goth.GetProviders() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var providers []map[string]interface{}
+	for _, name := range names {
+		providers = append(providers, map[string]interface{}{
+			"Name":  name,
+			"Title": providerTitles[name],
+		})
+	}
+	return providers
+}
+
 func getLoginPage(w http.ResponseWriter, r *http.Request) {
 	settings := model.GetSettingsWithoutModel()
 	w.Write([]byte(loginTemplate.Render(
-		map[string]string{"AppTitle": getAppTitle(settings)})))
+		map[string]interface{}{
+			"AppTitle":  getAppTitle(settings),
+			"Providers": loginProviders(),
+		})))
+}
+
+func getPendingPage(w http.ResponseWriter, r *http.Request) {
+	settings := model.GetSettingsWithoutModel()
+	w.Write([]byte(loginTemplate.Render(
+		map[string]interface{}{
+			"AppTitle":  getAppTitle(settings),
+			"Providers": loginProviders(),
+			"message":   "Tunnuksesi odottaa ylläpitäjän hyväksyntää.",
+		})))
 }
 
 func getDocuments(m *model.Model, w http.ResponseWriter, r *http.Request) {
 	settings := m.GetSettings()
-	documents := m.GetDocuments()
+	filter := r.FormValue("tila")
+	documents := m.GetDocuments(filter)
+	var dataQuality model.DataQuality
+	if m.User().IsAdmin {
+		dataQuality = m.GetDataQuality()
+	}
 	w.Write([]byte(documentsTemplate.Render(
 		map[string]interface{}{
-			"AppTitle":    getAppTitle(settings),
-			"IsPublic":    (publicURL != ""),
-			"CurrentUser": m.User(),
+			"AppTitle":     getAppTitle(settings),
+			"IsPublic":     (publicURL != ""),
+			"CurrentUser":  m.User(),
+			"FilterAll":    (filter == model.DocumentFilterAll),
+			"FilterOpen":   (filter == model.DocumentFilterOpen),
+			"FilterClosed": (filter == model.DocumentFilterClosed),
 			"Documents": map[string][]model.Document{
 				"Documents": documents,
 			},
+			"DataQuality": dataQuality,
 		})))
 }
 
@@ -204,10 +372,14 @@ func getDocumentID(m *model.Model, w http.ResponseWriter, r *http.Request) {
 	var users []model.User
 	var creditAccounts []model.Account
 	var debitAccounts []model.Account
+	var projects []model.Project
+	var auditLog []model.AuditEntry
 	if m.User().IsAdmin {
 		users = m.GetUsers(document.PaidUser.UserID)
 		creditAccounts = m.GetAccountList(false, document.CreditAccountID)
 		debitAccounts = m.GetAccountList(false, document.DebitAccountID)
+		projects = m.GetProjects(document.ProjectID)
+		auditLog = m.GetAuditLog(documentID)
 	}
 	w.Write([]byte(documentTemplate.Render(
 		map[string]interface{}{
@@ -217,6 +389,9 @@ func getDocumentID(m *model.Model, w http.ResponseWriter, r *http.Request) {
 			"Users":          users,
 			"CreditAccounts": creditAccounts,
 			"DebitAccounts":  debitAccounts,
+			"Projects":       projects,
+			"AuditLog":       auditLog,
+			"HasAuditLog":    len(auditLog) > 0,
 		})))
 }
 
@@ -230,6 +405,7 @@ func documentFromRequest(r *http.Request, documentID string) model.Document {
 		Amount:          r.PostFormValue("amount"),
 		CreditAccountID: r.PostFormValue("credit_account_id"),
 		DebitAccountID:  r.PostFormValue("debit_account_id"),
+		ProjectID:       r.PostFormValue("project_id"),
 		PaidUser: model.User{
 			UserID: int64(paidUserID),
 		},
@@ -239,6 +415,39 @@ func documentFromRequest(r *http.Request, documentID string) model.Document {
 func putDocumentID(m *model.Model, w http.ResponseWriter, r *http.Request) {
 	documentID := mux.Vars(r)["documentID"]
 	m.PutDocument(documentFromRequest(r, documentID))
+	if m.Err == model.ErrDocumentClosed {
+		m.Err = nil
+		http.Error(w, "Tosite on lukittu", http.StatusConflict)
+		return
+	}
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/tosite/"+documentID, http.StatusSeeOther)
+}
+
+func closeDocumentID(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	documentID := mux.Vars(r)["documentID"]
+	m.CloseDocument(documentID, r.PostFormValue("closed_type"))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/tosite/"+documentID, http.StatusSeeOther)
+}
+
+func putReimbursement(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	documentID := mux.Vars(r)["documentID"]
+	m.PutReimbursement(documentID, r.PostFormValue("reimbursement_state"),
+		r.PostFormValue("reimbursed_date_fi"))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/tosite/"+documentID, http.StatusSeeOther)
+}
+
+func reopenDocumentID(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	documentID := mux.Vars(r)["documentID"]
+	m.ReopenDocument(documentID)
 	if m.Err != nil {
 		return
 	}
@@ -257,9 +466,11 @@ func getNewDocumentPage(m *model.Model, w http.ResponseWriter, r *http.Request)
 	settings := m.GetSettings()
 	var users []model.User
 	var accounts []model.Account
+	var projects []model.Project
 	if m.User().IsAdmin {
 		users = m.GetUsers(0)
 		accounts = m.GetAccountList(false, "")
+		projects = m.GetProjects("")
 	}
 	w.Write([]byte(documentTemplate.Render(
 		map[string]interface{}{
@@ -268,30 +479,453 @@ func getNewDocumentPage(m *model.Model, w http.ResponseWriter, r *http.Request)
 			"Users":          users,
 			"CreditAccounts": accounts,
 			"DebitAccounts":  accounts,
+			"Projects":       projects,
+		})))
+}
+
+func getInvoices(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	settings := m.GetSettings()
+	w.Write([]byte(invoicesTemplate.Render(
+		map[string]interface{}{
+			"AppTitle":           getAppTitle(settings),
+			"CurrentUser":        m.User(),
+			"Invoices":           m.GetInvoices(),
+			"ExpectedPayments":   m.GetExpectedPayments(),
+			"ReceivableAccounts": m.GetAccountList(false, ""),
+		})))
+}
+
+func postExpectedPayment(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	m.PostExpectedPayment(model.ExpectedPayment{
+		ReferenceNumber:     r.PostFormValue("reference_number"),
+		Description:         r.PostFormValue("description"),
+		Amount:              r.PostFormValue("amount"),
+		DueDateFi:           r.PostFormValue("due_date_fi"),
+		ReceivableAccountID: r.PostFormValue("receivable_account_id"),
+	})
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/laskut", http.StatusSeeOther)
+}
+
+func invoiceFromRequest(r *http.Request, invoiceID string) model.Invoice {
+	return model.Invoice{
+		InvoiceID:           invoiceID,
+		CustomerName:        r.PostFormValue("customer_name"),
+		CustomerAddress:     r.PostFormValue("customer_address"),
+		Description:         r.PostFormValue("description"),
+		InvoiceDateFi:       r.PostFormValue("invoice_date_fi"),
+		DueDateFi:           r.PostFormValue("due_date_fi"),
+		Amount:              r.PostFormValue("amount"),
+		ReceivableAccountID: r.PostFormValue("receivable_account_id"),
+		RevenueAccountID:    r.PostFormValue("revenue_account_id"),
+	}
+}
+
+func getInvoicePage(m *model.Model, w http.ResponseWriter, r *http.Request,
+	invoice *model.Invoice) {
+	settings := m.GetSettings()
+	var receivableAccountID, revenueAccountID string
+	if invoice != nil {
+		receivableAccountID = invoice.ReceivableAccountID
+		revenueAccountID = invoice.RevenueAccountID
+	}
+	w.Write([]byte(invoiceTemplate.Render(
+		map[string]interface{}{
+			"AppTitle":           getAppTitle(settings),
+			"CurrentUser":        m.User(),
+			"Invoice":            invoice,
+			"ReceivableAccounts": m.GetAccountList(false, receivableAccountID),
+			"RevenueAccounts":    m.GetAccountList(false, revenueAccountID),
+		})))
+}
+
+func getNewInvoicePage(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	getInvoicePage(m, w, r, nil)
+}
+
+func getInvoiceID(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	invoice := m.GetInvoice(mux.Vars(r)["invoiceID"])
+	if m.Err != nil {
+		return
+	}
+	if invoice == nil {
+		http.NotFound(w, r)
+		return
+	}
+	getInvoicePage(m, w, r, invoice)
+}
+
+func postInvoice(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	invoiceID := m.PostInvoice(invoiceFromRequest(r, ""))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/lasku/"+invoiceID, http.StatusSeeOther)
+}
+
+func putInvoiceID(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	invoiceID := mux.Vars(r)["invoiceID"]
+	m.PutInvoice(invoiceFromRequest(r, invoiceID))
+	if m.Err == model.ErrInvoicePosted {
+		m.Err = nil
+		http.Error(w, "Lasku on jo kirjattu", http.StatusConflict)
+		return
+	}
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/lasku/"+invoiceID, http.StatusSeeOther)
+}
+
+func postInvoiceToBooks(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	invoiceID := mux.Vars(r)["invoiceID"]
+	m.PostInvoiceToBooks(invoiceID)
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/lasku/"+invoiceID, http.StatusSeeOther)
+}
+
+func getInvoicePdf(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	invoiceID := mux.Vars(r)["invoiceID"]
+	if m.GetInvoice(invoiceID) == nil {
+		http.NotFound(w, r)
+		return
+	}
+	report(func(m *model.Model, getWriter reports.GetWriter) error {
+		return reports.InvoicePdf(m, getWriter, invoiceID)
+	})(m, w, r)
+}
+
+func periodIDFromRequest(r *http.Request) int64 {
+	periodID, _ := strconv.ParseInt(r.FormValue("kausi"), 10, 64)
+	return periodID
+}
+
+func getMembers(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	settings := m.GetSettings()
+	var periodID int64
+	if period := m.GetPeriod(periodIDFromRequest(r)); period != nil {
+		periodID = period.PeriodID
+	}
+	w.Write([]byte(membersTemplate.Render(
+		map[string]interface{}{
+			"AppTitle":       getAppTitle(settings),
+			"CurrentUser":    m.User(),
+			"Members":        m.GetMembers(),
+			"Periods":        m.GetPeriods(periodID),
+			"PeriodID":       periodID,
+			"MemberFees":     m.GetMemberFees(periodID),
+			"UnpaidFees":     m.GetUnpaidMemberFees(periodID),
+			"Accounts":       m.GetAccountList(false, ""),
+			"ChargedMembers": r.FormValue("laskutettu"),
 		})))
 }
 
+func memberFromRequest(r *http.Request, memberID string) model.Member {
+	return model.Member{
+		MemberID:       memberID,
+		FullName:       r.PostFormValue("full_name"),
+		Email:          r.PostFormValue("email"),
+		Phone:          r.PostFormValue("phone"),
+		Address:        r.PostFormValue("address"),
+		MembershipType: r.PostFormValue("membership_type"),
+		JoinDateFi:     r.PostFormValue("join_date_fi"),
+		LeaveDateFi:    r.PostFormValue("leave_date_fi"),
+	}
+}
+
+func postMember(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	memberID := m.PostMember(memberFromRequest(r, ""))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/jasen/"+memberID, http.StatusSeeOther)
+}
+
+func getMemberID(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	memberID := mux.Vars(r)["memberID"]
+	member := m.GetMember(memberID)
+	if m.Err != nil {
+		return
+	}
+	if member == nil {
+		http.NotFound(w, r)
+		return
+	}
+	settings := m.GetSettings()
+	w.Write([]byte(memberTemplate.Render(
+		map[string]interface{}{
+			"AppTitle":    getAppTitle(settings),
+			"CurrentUser": m.User(),
+			"Member":      member,
+			"MemberFees":  m.GetMemberFeesOfMember(memberID),
+		})))
+}
+
+func putMemberID(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	memberID := mux.Vars(r)["memberID"]
+	m.PutMember(memberFromRequest(r, memberID))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/jasen/"+memberID, http.StatusSeeOther)
+}
+
+func putMemberFeeDocument(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	memberID := mux.Vars(r)["memberID"]
+	m.PutMemberFeeDocument(memberID, periodIDFromRequest(r),
+		r.PostFormValue("document_id"))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/jasen/"+memberID, http.StatusSeeOther)
+}
+
+func postMemberFees(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	periodID := periodIDFromRequest(r)
+	count := m.ChargeMemberFees(periodID,
+		r.PostFormValue("membership_type"),
+		r.PostFormValue("amount"),
+		r.PostFormValue("receivable_account_id"),
+		r.PostFormValue("revenue_account_id"))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/jasenet?kausi=%d&laskutettu=%d",
+		periodID, count), http.StatusSeeOther)
+}
+
+func getUnpaidMembersCsv(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	periodID := periodIDFromRequest(r)
+	report(func(m *model.Model, getWriter reports.GetWriter) error {
+		return reports.UnpaidMembersCsv(m, getWriter, periodID)
+	})(m, w, r)
+}
+
+func getBudget(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	settings := m.GetSettings()
+	comparison := m.GetBudgetComparison(periodIDFromRequest(r))
+	if m.Err != nil {
+		return
+	}
+	var periods []model.Period
+	if comparison != nil {
+		periods = m.GetPeriods(comparison.Period.PeriodID)
+	}
+	w.Write([]byte(budgetTemplate.Render(
+		map[string]interface{}{
+			"AppTitle":    getAppTitle(settings),
+			"CurrentUser": m.User(),
+			"Periods":     periods,
+			"Comparison":  comparison,
+		})))
+}
+
+func putBudget(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	periodID := periodIDFromRequest(r)
+	if err := r.ParseForm(); err != nil {
+		m.Err = err
+		return
+	}
+	amounts := map[int]string{}
+	for name, values := range r.PostForm {
+		if !strings.HasPrefix(name, "budget_") {
+			continue
+		}
+		accountID, err := strconv.Atoi(strings.TrimPrefix(name, "budget_"))
+		if err != nil {
+			http.Error(w, "Bad account ID", http.StatusBadRequest)
+			return
+		}
+		amounts[accountID] = values[0]
+	}
+	m.PutBudget(periodID, amounts)
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/talousarvio?kausi=%d", periodID),
+		http.StatusSeeOther)
+}
+
+func getBudgetPdf(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	periodID := periodIDFromRequest(r)
+	report(func(m *model.Model, getWriter reports.GetWriter) error {
+		return reports.BudgetComparisonPdf(m, getWriter, periodID)
+	})(m, w, r)
+}
+
+// getReimbursements shows the approved reimbursements that the SEPA
+// payment batch is made of, for marking them paid once the bank has
+// accepted the batch.
+func getReimbursements(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	settings := m.GetSettings()
+	reimbursements := m.GetApprovedReimbursements()
+	if m.Err != nil {
+		return
+	}
+	w.Write([]byte(reimbursementsTemplate.Render(
+		map[string]interface{}{
+			"AppTitle":          getAppTitle(settings),
+			"CurrentUser":       m.User(),
+			"Reimbursements":    reimbursements,
+			"HasReimbursements": len(reimbursements) > 0,
+			"Today":             time.Now().Format("2.1.2006"),
+			"PaidCount":         r.FormValue("maksettu"),
+		})))
+}
+
+func postReimbursementsPaid(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		m.Err = err
+		return
+	}
+	documentIDs := r.PostForm["document_id"]
+	m.PayReimbursements(documentIDs, r.PostFormValue("paid_date_fi"))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/kulukorvaukset?maksettu=%d",
+		len(documentIDs)), http.StatusSeeOther)
+}
+
+func getFullStatementZip(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	pdfA, err := reports.ParsePdfA(r.FormValue("pdfa"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report(func(m *model.Model, getWriter reports.GetWriter) error {
+		return reports.FullStatementZip(m, getWriter, pdfA)
+	})(m, w, r)
+}
+
+func getAccountStatement(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.Atoi(mux.Vars(r)["accountID"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := m.GetAccountMap()[accountID]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	tabularReport(func(m *model.Model, getWriter reports.GetWriter,
+		opts reports.Options) error {
+		return reports.AccountStatement(m, getWriter, opts, accountID)
+	})(m, w, r)
+}
+
 func getSettings(m *model.Model, w http.ResponseWriter, r *http.Request) {
 	settings := m.GetSettings()
 	users := m.GetUsers(0)
+	pendingUsers := m.GetPendingUsers()
 	w.Write([]byte(settingsTemplate.Render(
+		map[string]interface{}{
+			"AppTitle":     getAppTitle(settings),
+			"CurrentUser":  m.User(),
+			"Settings":     settings,
+			"Users":        users,
+			"PendingUsers": pendingUsers,
+			"BankAccounts": m.GetAccountList(false, settings.BankAccountID),
+			"CashAccounts": m.GetCashAccounts(),
+			"Projects":     m.GetProjects(""),
+		})))
+}
+
+func getUserPage(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	settings := m.GetSettings()
+	linked := m.GetUserAuthProviders()
+	var providers []map[string]interface{}
+	if publicURL != "" {
+		providers = loginProviders()
+		for _, provider := range providers {
+			isLinked := false
+			for _, linkedName := range linked {
+				isLinked = isLinked || (linkedName == provider["Name"])
+			}
+			provider["IsLinked"] = isLinked
+		}
+	}
+	w.Write([]byte(userTemplate.Render(
 		map[string]interface{}{
 			"AppTitle":    getAppTitle(settings),
 			"CurrentUser": m.User(),
-			"Settings":    settings,
-			"Users":       users,
+			"IsPublic":    (publicURL != ""),
+			"Providers":   providers,
+			"Balances":    m.GetReimbursementBalances(model.ReportFilter{}),
 		})))
 }
 
+func putUserPage(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	m.PutUserIBAN(r.PostFormValue("iban"))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/kayttaja", http.StatusSeeOther)
+}
+
 func getAboutPage(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(aboutTemplate.Render(nil)))
 }
 
 func putSettings(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	cashAccounts, err := model.ParseAccountRanges(
+		r.PostFormValue("CashAccounts"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m.PutCashAccounts(cashAccounts)
 	m.PutSettings(model.Settings{
-		OrgFullName:  r.PostFormValue("OrgFullName"),
-		OrgShortName: r.PostFormValue("OrgShortName"),
+		OrgFullName:         r.PostFormValue("OrgFullName"),
+		OrgShortName:        r.PostFormValue("OrgShortName"),
+		OrgIBAN:             r.PostFormValue("OrgIBAN"),
+		OrgBIC:              r.PostFormValue("OrgBIC"),
+		BankAccountID:       r.PostFormValue("BankAccountID"),
+		StatementNotes:      r.PostFormValue("StatementNotes"),
+		StatementSignatures: r.PostFormValue("StatementSignatures"),
 	})
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/asetukset", http.StatusSeeOther)
+}
+
+func postProject(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	m.PostProject(r.PostFormValue("title"))
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/asetukset", http.StatusSeeOther)
+}
+
+func putUserApproval(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	m.ApproveUser(int64(userID), r.PostFormValue("approve") == "1")
+	if m.Err != nil {
+		return
+	}
+	http.Redirect(w, r, "/asetukset", http.StatusSeeOther)
+}
+
+func postMergeUsers(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	fromUserID, err1 := strconv.Atoi(r.PostFormValue("from_user_id"))
+	intoUserID, err2 := strconv.Atoi(r.PostFormValue("into_user_id"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	m.MergeUsers(int64(fromUserID), int64(intoUserID))
+	if m.Err != nil {
+		return
+	}
 	http.Redirect(w, r, "/asetukset", http.StatusSeeOther)
 }
 
@@ -306,6 +940,25 @@ func getApiCompare(m *model.Model, w http.ResponseWriter, r *http.Request) {
 	w.Write(bytes)
 }
 
+func postApiPayments(m *model.Model, w http.ResponseWriter, r *http.Request) {
+	var bankPayments []model.BankPayment
+	if err := json.NewDecoder(r.Body).Decode(&bankPayments); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	results := m.PostBankPayments(bankPayments)
+	if m.Err != nil {
+		return
+	}
+	bytes, err := json.Marshal(results)
+	if err != nil {
+		m.Err = err
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
 func getCompare(m *model.Model, w http.ResponseWriter, r *http.Request) {
 	settings := m.GetSettings()
 	w.Write([]byte(compareTemplate.Render(
@@ -351,14 +1004,86 @@ func postImage(m *model.Model, w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(imageID))
 }
 
-func report(generate func(*model.Model, reports.GetWriter)) ModelHandlerFunc {
+// reportWriter remembers whether anything has been sent to the client,
+// after which an error can no longer be turned into an error page.
+type reportWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (rw *reportWriter) Write(p []byte) (int, error) {
+	rw.written = true
+	return rw.ResponseWriter.Write(p)
+}
+
+func (rw *reportWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// report runs a report generator on the response. If the report fails
+// before any of it is sent, the client gets a 500 response instead.
+func report(generate func(*model.Model, reports.GetWriter) error) ModelHandlerFunc {
 	return func(m *model.Model, w http.ResponseWriter, r *http.Request) {
-		generate(m, func(mimeType, filename string) (io.Writer, error) {
+		rw := &reportWriter{ResponseWriter: w}
+		err := generate(m, func(mimeType, filename string) (io.Writer, error) {
 			w.Header().Set("Content-Type", mimeType)
 			w.Header().Set("Content-Disposition",
 				fmt.Sprintf("inline; filename=%q", filename))
-			return w, nil
+			return rw, nil
 		})
+		if err == nil || m.Err != nil {
+			return
+		}
+		// Whatever the report changed is only kept if it was sent.
+		m.Discard()
+		if userErr, ok := err.(reports.UserError); ok && !rw.written {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, userErr.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("Raportti %s: %v", r.URL.Path, err)
+		if !rw.written {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, http.StatusText(http.StatusInternalServerError),
+				http.StatusInternalServerError)
+		}
+	}
+}
+
+// tabularReport passes the format and filter chosen with the query
+// parameters to a report: format, projekti, tilit (e.g. 3000-3999),
+// alkaen and asti (pp.kk.vvvv), tositteet (e.g. 10-20), siirto to
+// carry debit and credit totals from page to page, and pdfa (1 or 2)
+// for PDF/A.
+func tabularReport(generate func(*model.Model, reports.GetWriter, reports.Options) error) ModelHandlerFunc {
+	return func(m *model.Model, w http.ResponseWriter, r *http.Request) {
+		filter, err := model.ParseReportFilter(r.FormValue("projekti"),
+			r.FormValue("tilit"), r.FormValue("alkaen"),
+			r.FormValue("asti"), r.FormValue("tositteet"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pdfA, err := reports.ParsePdfA(r.FormValue("pdfa"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts := reports.Options{
+			Format:       r.FormValue("format"),
+			ReportFilter: filter,
+			CarryForward: r.FormValue("siirto") != "",
+			PdfA:         pdfA,
+		}
+		if !reports.IsValidFormat(opts.Format) {
+			http.Error(w, "Unknown format", http.StatusBadRequest)
+			return
+		}
+		report(func(m *model.Model, getWriter reports.GetWriter) error {
+			return generate(m, getWriter, opts)
+		})(m, w, r)
 	}
 }
 
@@ -416,6 +1141,15 @@ func main() {
 				os.Getenv("GOOGLE_CLIENT_SECRET"),
 				publicURL+"/auth/google/callback"),
 		)
+		if os.Getenv("GITHUB_CLIENT_ID") != "" {
+			goth.UseProviders(
+				github.New(
+					os.Getenv("GITHUB_CLIENT_ID"),
+					os.Getenv("GITHUB_CLIENT_SECRET"),
+					publicURL+"/auth/github/callback",
+					"user:email"),
+			)
+		}
 	}
 
 	router := mux.NewRouter()
@@ -437,38 +1171,115 @@ func main() {
 		anyUser(getDocumentID))
 	post(`/tosite/{documentID}`,
 		anyUser(putDocumentID))
+	post(`/tosite/{documentID}/lukitse`,
+		adminOnly(closeDocumentID))
+	post(`/tosite/{documentID}/avaa`,
+		adminOnly(reopenDocumentID))
+	post(`/tosite/{documentID}/korvaus`,
+		adminOnly(putReimbursement))
 	get(`/tosite`,
 		anyUser(getNewDocumentPage))
 	post(`/tosite`,
 		anyUser(postDocument))
 
+	get(`/laskut`,
+		adminOnly(getInvoices))
+	get(`/lasku`,
+		adminOnly(getNewInvoicePage))
+	post(`/lasku`,
+		adminOnly(postInvoice))
+	get(`/lasku/{invoiceID}`,
+		adminOnly(getInvoiceID))
+	post(`/lasku/{invoiceID}`,
+		adminOnly(putInvoiceID))
+	post(`/lasku/{invoiceID}/kirjaa`,
+		adminOnly(postInvoiceToBooks))
+	get(`/lasku/{invoiceID}/pdf`,
+		adminOnly(getInvoicePdf))
+	get(`/jasenet`,
+		adminOnly(getMembers))
+	post(`/jasen`,
+		adminOnly(postMember))
+	get(`/jasen/{memberID}`,
+		adminOnly(getMemberID))
+	post(`/jasen/{memberID}`,
+		adminOnly(putMemberID))
+	post(`/jasen/{memberID}/jasenmaksu`,
+		adminOnly(putMemberFeeDocument))
+	post(`/jasenmaksut`,
+		adminOnly(postMemberFees))
+	get(`/raportti/maksamattomat-jasenmaksut`,
+		adminOnly(getUnpaidMembersCsv))
+	get(`/talousarvio`,
+		adminOnly(getBudget))
+	post(`/talousarvio`,
+		adminOnly(putBudget))
+	get(`/raportti/talousarvio`,
+		adminOnly(getBudgetPdf))
 	post(`/api/settings`,
 		adminOnly(putSettings))
+	post(`/api/users/{userID}/approval`,
+		adminOnly(putUserApproval))
+	post(`/api/users/merge`,
+		adminOnly(postMergeUsers))
+	post(`/api/projects`,
+		adminOnly(postProject))
 	get(`/api/compare`,
 		adminOnly(getApiCompare))
+	post(`/api/payments`,
+		adminOnly(postApiPayments))
+	post(`/odotettu-maksu`,
+		adminOnly(postExpectedPayment))
 	get(`/asetukset`,
 		adminOnly(getSettings))
+	get(`/kayttaja`,
+		anyUser(getUserPage))
+	post(`/kayttaja`,
+		anyUser(putUserPage))
 	get(`/tietoja`,
 		getAboutPage)
 	get(`/vertaa`,
 		adminOnly(getCompare))
 	get(`/raportti/tuloslaskelma`,
-		adminOnly(report(reports.IncomeStatementPdf)))
+		adminOnly(tabularReport(reports.IncomeStatement)))
 	get(`/raportti/tuloslaskelma-erittelyin`,
-		adminOnly(report(reports.IncomeStatementDetailedPdf)))
+		adminOnly(tabularReport(reports.IncomeStatementDetailed)))
 	get(`/raportti/tase`,
-		adminOnly(report(reports.BalanceSheetPdf)))
+		adminOnly(tabularReport(reports.BalanceSheet)))
 	get(`/raportti/tase-erittelyin`,
-		adminOnly(report(reports.BalanceSheetDetailedPdf)))
+		adminOnly(tabularReport(reports.BalanceSheetDetailed)))
+	get(`/raportti/rahoituslaskelma`,
+		adminOnly(tabularReport(reports.CashFlow)))
 	get(`/raportti/paivakirja`,
-		adminOnly(report(reports.GeneralJournalPdf)))
+		adminOnly(tabularReport(reports.GeneralJournal)))
 	get(`/raportti/paakirja`,
-		adminOnly(report(reports.GeneralLedgerPdf)))
+		adminOnly(tabularReport(reports.GeneralLedger)))
+	get(`/raportti/projektit`,
+		adminOnly(tabularReport(reports.ProjectSummary)))
+	get(`/raportti/tiliote/{accountID}`,
+		adminOnly(getAccountStatement))
+	get(`/raportti/saldoluettelo`,
+		adminOnly(tabularReport(reports.TrialBalance)))
 	get(`/raportti/tilikartta`,
-		adminOnly(report(reports.ChartOfAccountsPdf)))
+		adminOnly(tabularReport(reports.ChartOfAccounts)))
+	get(`/raportti/kulukorvaukset`,
+		adminOnly(tabularReport(reports.Reimbursements)))
+	get(`/raportti/kulukorvaukset-sepa`,
+		adminOnly(report(reports.ReimbursementsSepaXml)))
+	get(`/kulukorvaukset`,
+		adminOnly(getReimbursements))
+	post(`/kulukorvaukset`,
+		adminOnly(postReimbursementsPaid))
+	get(`/raportti/muutosloki`,
+		adminOnly(tabularReport(reports.AuditLog)))
+	get(`/raportti/tietojen-laatu`,
+		adminOnly(tabularReport(reports.DataQuality)))
+	get(`/raportti/tositteet`,
+		adminOnly(tabularReport(reports.ReceiptsPdf)))
 	get(`/raportti/tilinpaatos`,
-		adminOnly(report(reports.FullStatementZip)))
+		adminOnly(getFullStatementZip))
 
+	get(`/linkita/{provider}`, beginLinkLogin)
 	get(`/auth/{provider}/callback`, finishLogin)
 	get(`/auth/{provider}`, gothic.BeginAuthHandler)
 	get(`/ulos`, logout)
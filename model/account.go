@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -26,7 +27,9 @@ type Account struct {
 	AccountIDStr string
 	Prefix       string
 	Title        string
-	IsMatch      bool
+	// Cash and bank accounts, whose entries make up the cash flow.
+	IsCash  bool
+	IsMatch bool
 }
 
 type AccountRange struct {
@@ -47,16 +50,69 @@ func AccountIDInRange(accountID int, ranges []AccountRange) bool {
 	return false
 }
 
+// ParseAccountRanges parses a comma-separated list of account numbers
+// and inclusive ranges of them, e.g. "1910,3000-3999".
+func ParseAccountRanges(s string) ([]AccountRange, error) {
+	var ranges []AccountRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid account range: %q", part)
+		}
+		end := start
+		if len(bounds) == 2 {
+			end, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil || end < start {
+				return nil, fmt.Errorf("Invalid account range: %q", part)
+			}
+		}
+		ranges = append(ranges, AccountRange{Start: start, Limit: end + 1})
+	}
+	return ranges, nil
+}
+
+// FormatAccountRanges is the inverse of ParseAccountRanges.
+func FormatAccountRanges(ranges []AccountRange) string {
+	var parts []string
+	for _, ar := range ranges {
+		if ar.Limit == ar.Start+1 {
+			parts = append(parts, strconv.Itoa(ar.Start))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", ar.Start, ar.Limit-1))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// latestPeriod picks the rows of the chart of the latest period.
+const latestPeriod = "period_id = (select period_id from period" +
+	" order by start_date desc, period_id desc limit 1)"
+
+// selectAccount selects the chart of accounts of the latest period.
 func selectAccount() sq.SelectBuilder {
-	return sq.Select("account_id, account_type, title, nesting_level").
+	return sq.Select("account_id, account_type, title, nesting_level, is_cash").
 		From("period_account").
+		Where(latestPeriod).
+		OrderBy("account_id, nesting_level")
+}
+
+// selectPeriodAccount selects the chart of accounts of the period.
+func selectPeriodAccount(periodID int64) sq.SelectBuilder {
+	return sq.Select("account_id, account_type, title, nesting_level, is_cash").
+		From("period_account").
+		Where(sq.Eq{"period_id": periodID}).
 		OrderBy("account_id, nesting_level")
 }
 
 func scanAccount(rows sq.RowScanner) (Account, error) {
 	var a Account
 	if err := rows.Scan(&a.AccountID, &a.AccountType,
-		&a.Title, &a.NestingLevel); err != nil {
+		&a.Title, &a.NestingLevel, &a.IsCash); err != nil {
 		return a, err
 	}
 	if a.IsHeading() {
@@ -92,8 +148,14 @@ func (m *Model) GetAccountList(usedOnly bool, matchAccountID string) []Account {
 }
 
 func (m *Model) GetAccountMap() map[int]Account {
+	return m.accountMapFromSelect(selectAccount())
+}
+
+// accountMapFromSelect maps the numbers of the selected accounts to
+// the accounts, leaving out the headings.
+func (m *Model) accountMapFromSelect(q sq.SelectBuilder) map[int]Account {
 	acctMap := map[int]Account{}
-	rows, err := selectAccount().RunWith(m.tx).Query()
+	rows, err := q.RunWith(m.tx).Query()
 	if m.isErr(err) {
 		return acctMap
 	}
@@ -109,3 +171,56 @@ func (m *Model) GetAccountMap() map[int]Account {
 	}
 	return acctMap
 }
+
+// GetCashAccounts returns the numbers of the cash and bank accounts in
+// the form that ParseAccountRanges reads.
+func (m *Model) GetCashAccounts() string {
+	var ranges []AccountRange
+	for _, acct := range m.GetAccountList(false, "") {
+		if acct.IsHeading() || !acct.IsCash {
+			continue
+		}
+		ranges = append(ranges,
+			AccountRange{Start: acct.AccountID, Limit: acct.AccountID + 1})
+	}
+	return FormatAccountRanges(ranges)
+}
+
+// PutCashAccounts flags the accounts in the ranges as cash and bank
+// accounts, and no others, in the chart of the latest period.
+func (m *Model) PutCashAccounts(ranges []AccountRange) {
+	if !m.isAdmin() {
+		return
+	}
+	wasCash := map[int]bool{}
+	for _, acct := range m.GetAccountList(false, "") {
+		if !acct.IsHeading() {
+			wasCash[acct.AccountID] = acct.IsCash
+		}
+	}
+	_, err := sq.Update("period_account").Set("is_cash", false).
+		Where(latestPeriod).
+		Where(sq.Eq{"nesting_level": accountNestingLevel}).
+		RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return
+	}
+	for _, ar := range ranges {
+		_, err = sq.Update("period_account").Set("is_cash", true).
+			Where(latestPeriod).
+			Where(sq.Eq{"nesting_level": accountNestingLevel}).
+			Where(sq.GtOrEq{"account_id": ar.Start}).
+			Where(sq.Lt{"account_id": ar.Limit}).
+			RunWith(m.tx).Exec()
+		if m.isErr(err) {
+			return
+		}
+	}
+	for _, acct := range m.GetAccountList(false, "") {
+		if !acct.IsHeading() {
+			m.audit(AuditAccount, acct.AccountIDStr, "is_cash",
+				strconv.FormatBool(wasCash[acct.AccountID]),
+				strconv.FormatBool(acct.IsCash))
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// AccountStatement lists the entries of one account with the balance
+// after each of them. Balances grow in the normal direction of the
+// account, i.e. debits increase assets and expenses and credits
+// increase the rest.
+type AccountStatement struct {
+	Account        Account
+	OpeningBalance string
+	ClosingBalance string
+	TotalDebit     string
+	TotalCredit    string
+	Entries        []LedgerEntry
+}
+
+// GetAccountStatement returns the statement of the account over the
+// dates of the filter, which default to the latest period. The opening
+// balance is the starting balance of the account in the period of the
+// start date plus the entries of that period before the start date.
+// With a project, only the entries of the project are counted, and as
+// the starting balances are not kept by project, the opening balance
+// holds just the entries. Returns nil if there is no such account.
+func (m *Model) GetAccountStatement(accountID int, filter ReportFilter) *AccountStatement {
+	if !m.isAdmin() {
+		return nil
+	}
+	filter = m.StatementFilter(filter)
+	acctMap := m.GetAccountMap()
+	acct, ok := acctMap[accountID]
+	if !ok {
+		return nil
+	}
+	q := sq.Select("document.document_id, document.paid_date, document_entry.debit",
+		"document_entry.unit_count * document_entry.unit_cost_cents",
+		"document_entry.description").
+		From("document_entry").
+		Join("document on (document.document_id = document_entry.document_id)").
+		Where(sq.Eq{"document_entry.account_id": accountID}).
+		OrderBy("document.paid_date, document.document_id, document_entry.row_number")
+	if filter.ProjectID != "" {
+		q = q.Where(sq.Eq{"document_entry.project_id": filter.ProjectID})
+	}
+	if filter.EndDateFi != "" {
+		q = q.Where(sq.LtOrEq{"document.paid_date": isoFromFiDate(filter.EndDateFi)})
+	}
+	if filter.StartDateFi != "" {
+		q = q.Where(sq.GtOrEq{"document.paid_date": isoFromFiDate(filter.StartDateFi)})
+	}
+	var balance int64
+	if filter.ProjectID != "" {
+		balance = m.projectOpeningBalances(acctMap, filter)[accountID]
+	} else {
+		opening, _ := m.getOpeningBalances(acctMap, filter.StartDateFi)
+		balance = opening[accountID]
+	}
+	openingBalance := balance
+	rows, err := q.RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return nil
+	}
+	defer rows.Close()
+	var debitCents, creditCents int64
+	var entries []LedgerEntry
+	for rows.Next() {
+		var entry LedgerEntry
+		var paidDate sql.NullString
+		var isDebit bool
+		var cents int64
+		if m.isErr(rows.Scan(&entry.DocumentID, &paidDate, &isDebit,
+			&cents, &entry.Description)) {
+			return nil
+		}
+		delta := cents
+		if entrySubtractsFromBalance(isDebit, acct.AccountType) {
+			delta = -cents
+		}
+		balance += delta
+		if isDebit {
+			entry.DebitAmount = amountFromCents(cents)
+			debitCents += cents
+		} else {
+			entry.CreditAmount = amountFromCents(cents)
+			creditCents += cents
+		}
+		entry.PaidDateISO = paidDate.String
+		entry.PaidDateFi = fiFromISODate(paidDate.String)
+		entry.BalanceAfter = amountFromCents(balance)
+		entries = append(entries, entry)
+	}
+	if m.isErr(rows.Err()) {
+		return nil
+	}
+	return &AccountStatement{
+		Account:        acct,
+		OpeningBalance: amountFromCents(openingBalance),
+		ClosingBalance: amountFromCents(balance),
+		TotalDebit:     amountFromCents(debitCents),
+		TotalCredit:    amountFromCents(creditCents),
+		Entries:        entries,
+	}
+}
+
+// projectOpeningBalances returns the balance of each account in the
+// entries of the project from the start of the period that the start
+// date of the filter falls in up to the day before the start date.
+func (m *Model) projectOpeningBalances(acctMap map[int]Account,
+	filter ReportFilter) map[int]int64 {
+	if filter.StartDateFi == "" {
+		return map[int]int64{}
+	}
+	before := ReportFilter{
+		ProjectID: filter.ProjectID,
+		EndDateFi: filter.dayBefore(),
+	}
+	if period := m.getPeriodAt(isoFromFiDate(filter.StartDateFi)); period != nil {
+		before.StartDateFi = period.StartDateFi
+	}
+	balances, _ := GetAccountBalancesAndProfit(acctMap,
+		before.filterEntries(m.getDocumentEntries(before)))
+	return balances
+}
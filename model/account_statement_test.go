@@ -0,0 +1,65 @@
+package model
+
+import "testing"
+
+func TestGetAccountStatement(t *testing.T) {
+	m := newTestModel(t)
+	if _, err := m.tx.Exec(`
+insert into project values (1, 'Kevätjuhla');
+insert into period values (2, '2027-01-01', '2027-12-31');
+insert into period_account
+ (period_id, account_id, account_type, title, nesting_level,
+  starting_balance_cents, is_cash)
+ select 2, account_id, account_type, title, nesting_level,
+  starting_balance_cents, is_cash
+ from period_account where period_id = 1;
+`); err != nil {
+		t.Fatal(err)
+	}
+	postProjectDocument := func(dateFi, amount, projectID string) {
+		m.PostDocument(Document{
+			PaidDateFi:      dateFi,
+			Description:     "Testi",
+			Amount:          amount,
+			DebitAccountID:  "4000",
+			CreditAccountID: "1910",
+			ProjectID:       projectID,
+		})
+		if m.Err != nil {
+			t.Fatal(m.Err)
+		}
+	}
+	postProjectDocument("5.3.2026", "12,50", "1")
+	postProjectDocument("10.1.2027", "20,00", "1")
+	postProjectDocument("15.1.2027", "30,00", "")
+	postProjectDocument("1.2.2027", "5,00", "1")
+	tests := []struct {
+		filter  ReportFilter
+		opening string
+		entries int
+		closing string
+	}{
+		// Without dates the statement covers the latest period.
+		{ReportFilter{}, "", 3, "55,00"},
+		{ReportFilter{ProjectID: "1"}, "", 2, "25,00"},
+		// The opening balance of a project leaves out the other
+		// entries before the start date.
+		{ReportFilter{ProjectID: "1", StartDateFi: "20.1.2027"},
+			"20,00", 1, "25,00"},
+		{ReportFilter{StartDateFi: "20.1.2027"}, "50,00", 1, "55,00"},
+	}
+	for i, test := range tests {
+		statement := m.GetAccountStatement(4000, test.filter)
+		if m.Err != nil {
+			t.Fatal(m.Err)
+		}
+		if statement.OpeningBalance != test.opening ||
+			len(statement.Entries) != test.entries ||
+			statement.ClosingBalance != test.closing {
+			t.Errorf("%d: %q with %d entries to %q, want %q with %d to %q",
+				i, statement.OpeningBalance, len(statement.Entries),
+				statement.ClosingBalance,
+				test.opening, test.entries, test.closing)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package model
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	AuditDocument      = "document"
+	AuditDocumentEntry = "document_entry"
+	AuditDocumentImage = "document_image"
+	AuditSetting       = "setting"
+	AuditAccount       = "account"
+	AuditBudget        = "budget"
+	AuditProject       = "project"
+	AuditPermission    = "permission"
+	AuditUser          = "user"
+)
+
+type AuditEntry struct {
+	ChangedTimeISO string
+	ChangedTimeFi  string
+	UserFullName   string
+	ObjectType     string
+	ObjectID       string
+	Field          string
+	OldValue       string
+	NewValue       string
+}
+
+// audit records a change made by the current user. Nothing is
+// recorded if the value did not actually change.
+func (m *Model) audit(objectType, objectID, field, oldValue, newValue string) {
+	if oldValue == newValue {
+		return
+	}
+	_, err := sq.Insert("audit_log").SetMap(sq.Eq{
+		"changed_time": time.Now().Format("2006-01-02 15:04:05"),
+		"user_id":      userIDOrNil(m.user.UserID),
+		"object_type":  objectType,
+		"object_id":    objectID,
+		"field":        field,
+		"old_value":    oldValue,
+		"new_value":    newValue,
+	}).RunWith(m.tx).Exec()
+	m.isErr(err)
+}
+
+func auditUserID(userID int64) string {
+	if userID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(userID, 10)
+}
+
+func scanAuditEntry(rows sq.RowScanner) (AuditEntry, error) {
+	var e AuditEntry
+	var userFullName sql.NullString
+	var oldValue sql.NullString
+	var newValue sql.NullString
+	err := rows.Scan(&e.ChangedTimeISO, &userFullName, &e.ObjectType,
+		&e.ObjectID, &e.Field, &oldValue, &newValue)
+	e.UserFullName = userFullName.String
+	e.OldValue = oldValue.String
+	e.NewValue = newValue.String
+	if t, err := time.Parse("2006-01-02 15:04:05", e.ChangedTimeISO); err == nil {
+		e.ChangedTimeFi = t.Format("2.1.2006 15.04")
+	}
+	return e, err
+}
+
+func selectAuditEntry() sq.SelectBuilder {
+	return sq.Select("changed_time, user.full_name, object_type, object_id, field, old_value, new_value").
+		From("audit_log").
+		LeftJoin("user on (user.user_id = audit_log.user_id)").
+		OrderBy("audit_id")
+}
+
+// GetAuditLog returns the change history of one document, or of
+// everything if documentID is empty.
+func (m *Model) GetAuditLog(documentID string) []AuditEntry {
+	q := selectAuditEntry()
+	if documentID != "" {
+		q = q.Where(sq.Eq{
+			"object_type": []string{AuditDocument,
+				AuditDocumentEntry, AuditDocumentImage},
+			"object_id": documentID,
+		})
+	}
+	return m.auditEntriesFromSelect(q)
+}
+
+// GetAuditLogOfPeriod returns the changes made between the dates of
+// the filter, which default to the latest period.
+func (m *Model) GetAuditLogOfPeriod(filter ReportFilter) []AuditEntry {
+	filter = m.StatementFilter(filter)
+	q := selectAuditEntry()
+	if filter.StartDateFi != "" {
+		q = q.Where("date(changed_time) >= ?", isoFromFiDate(filter.StartDateFi))
+	}
+	if filter.EndDateFi != "" {
+		q = q.Where("date(changed_time) <= ?", isoFromFiDate(filter.EndDateFi))
+	}
+	return m.auditEntriesFromSelect(q)
+}
+
+func (m *Model) auditEntriesFromSelect(q sq.SelectBuilder) []AuditEntry {
+	noEntries := []AuditEntry{}
+	if !m.isAdmin() {
+		return noEntries
+	}
+	rows, err := q.RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return noEntries
+	}
+	defer rows.Close()
+	entries := noEntries
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if m.isErr(err) {
+			return noEntries
+		}
+		entries = append(entries, entry)
+	}
+	if m.isErr(rows.Err()) {
+		return noEntries
+	}
+	return entries
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// BudgetRow compares the budgeted and actual balance of one income
+// statement account. Heading rows carry no amounts.
+type BudgetRow struct {
+	Account
+	Budget        string
+	BudgetCents   int64
+	Actual        string
+	ActualCents   int64
+	Variance      string
+	VarianceCents int64
+	Percent       string
+	IsTotal       bool
+}
+
+type BudgetComparison struct {
+	Period Period
+	Rows   []BudgetRow
+}
+
+func isIncomeStatementAccount(acct Account) bool {
+	return acct.AccountType == RevenueAccount ||
+		acct.AccountType == ExpenseAccount
+}
+
+func budgetPercent(actualCents, budgetCents int64) string {
+	if budgetCents == 0 {
+		return ""
+	}
+	// Round half away from zero whatever the signs are.
+	num, den := 200*actualCents, 2*budgetCents
+	if den < 0 {
+		num, den = -num, -den
+	}
+	if num < 0 {
+		return fmt.Sprintf("%d %%", -((-num + den/2) / den))
+	}
+	return fmt.Sprintf("%d %%", (num+den/2)/den)
+}
+
+func newBudgetRow(acct Account, budgetCents, actualCents int64) BudgetRow {
+	return BudgetRow{
+		Account:       acct,
+		Budget:        amountFromCents(budgetCents),
+		BudgetCents:   budgetCents,
+		Actual:        amountFromCents(actualCents),
+		ActualCents:   actualCents,
+		Variance:      amountFromCents(actualCents - budgetCents),
+		VarianceCents: actualCents - budgetCents,
+		Percent:       budgetPercent(actualCents, budgetCents),
+	}
+}
+
+// GetBudget returns the budgeted amounts of the period by account.
+func (m *Model) GetBudget(periodID int64) map[int]int64 {
+	budget := map[int]int64{}
+	rows, err := sq.Select("account_id, amount_cents").From("budget").
+		Where(sq.Eq{"period_id": periodID}).RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return budget
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var accountID int
+		var cents int64
+		if m.isErr(rows.Scan(&accountID, &cents)) {
+			return budget
+		}
+		budget[accountID] = cents
+	}
+	m.isErr(rows.Err())
+	return budget
+}
+
+// PutBudget sets the budgeted amounts of the period. Accounts not in
+// the map keep their old budget, and an empty amount removes it.
+func (m *Model) PutBudget(periodID int64, amounts map[int]string) {
+	if !m.isAdmin() {
+		return
+	}
+	oldBudget := m.GetBudget(periodID)
+	for accountID, amount := range amounts {
+		cents, err := SignedCentsFromAmount(amount)
+		if m.isErr(err) {
+			return
+		}
+		m.audit(AuditBudget, fmt.Sprintf("%d/%d", periodID, accountID),
+			"amount",
+			amountFromCents(oldBudget[accountID]),
+			amountFromCents(cents))
+		if cents == 0 {
+			_, err = sq.Delete("budget").Where(sq.Eq{
+				"period_id":  periodID,
+				"account_id": accountID,
+			}).RunWith(m.tx).Exec()
+		} else {
+			_, err = sq.Insert("budget").Options("or replace").
+				SetMap(sq.Eq{
+					"period_id":    periodID,
+					"account_id":   accountID,
+					"amount_cents": cents,
+				}).RunWith(m.tx).Exec()
+		}
+		if m.isErr(err) {
+			return
+		}
+	}
+}
+
+// GetBudgetComparison compares the budget of the period to the actual
+// balances of the revenue and expense accounts, with totals for
+// revenue, expenses and the result.
+func (m *Model) GetBudgetComparison(periodID int64) *BudgetComparison {
+	if !m.isAdmin() {
+		return nil
+	}
+	period := m.GetPeriod(periodID)
+	if period == nil {
+		return nil
+	}
+	budget := m.GetBudget(period.PeriodID)
+	acctMap := m.GetAccountMap()
+	balances, _ := GetAccountBalancesAndProfit(acctMap,
+		m.GetPeriodDocumentEntries(*period))
+	comparison := &BudgetComparison{Period: *period}
+	var budgetTotals, actualTotals [ExpenseAccount + 1]int64
+	for _, acct := range m.GetAccountList(false, "") {
+		if !isIncomeStatementAccount(acct) {
+			continue
+		}
+		if acct.IsHeading() {
+			comparison.Rows = append(comparison.Rows,
+				BudgetRow{Account: acct})
+			continue
+		}
+		budgetCents := budget[acct.AccountID]
+		actualCents := balances[acct.AccountID]
+		budgetTotals[acct.AccountType] += budgetCents
+		actualTotals[acct.AccountType] += actualCents
+		comparison.Rows = append(comparison.Rows,
+			newBudgetRow(acct, budgetCents, actualCents))
+	}
+	total := func(title string, budgetCents, actualCents int64) {
+		row := newBudgetRow(Account{
+			Title:        title,
+			NestingLevel: accountNestingLevel,
+		}, budgetCents, actualCents)
+		row.IsTotal = true
+		comparison.Rows = append(comparison.Rows, row)
+	}
+	total("Tuotot yhteensä", budgetTotals[RevenueAccount],
+		actualTotals[RevenueAccount])
+	total("Kulut yhteensä", budgetTotals[ExpenseAccount],
+		actualTotals[ExpenseAccount])
+	total("Tilikauden tulos",
+		budgetTotals[RevenueAccount]-budgetTotals[ExpenseAccount],
+		actualTotals[RevenueAccount]-actualTotals[ExpenseAccount])
+	return comparison
+}
@@ -0,0 +1,157 @@
+package model
+
+// CashFlowRow is the money that came in or went out with entries on
+// one kind of counter-account.
+type CashFlowRow struct {
+	Title       string
+	Amount      string
+	AmountCents int64
+}
+
+// CashFlow (rahoituslaskelma) follows the money on the cash and bank
+// accounts from the start of the period to its end. Outflows are
+// positive amounts.
+type CashFlow struct {
+	Period            string
+	CashAccounts      []Account
+	OpeningCash       string
+	OpeningCashCents  int64
+	Inflows           []CashFlowRow
+	TotalInflow       string
+	TotalInflowCents  int64
+	Outflows          []CashFlowRow
+	TotalOutflow      string
+	TotalOutflowCents int64
+	ClosingCash       string
+	ClosingCashCents  int64
+}
+
+// cashFlowGroupOther collects cash that the other entries of the
+// document do not account for, which happens only when debits and
+// credits differ.
+const cashFlowGroupOther = -1
+
+// cashFlowGroup gives the kind of counter-account that the cash flow
+// is grouped by.
+func cashFlowGroup(acctType int) int {
+	switch acctType {
+	case PastProfitAccount, ProfitAccount:
+		return EquityAccount
+	}
+	return acctType
+}
+
+// cashFlowGroups are the groups in the order they are listed.
+var cashFlowGroups = []struct {
+	group int
+	title string
+}{
+	{RevenueAccount, "Tulot"},
+	{ExpenseAccount, "Menot"},
+	{AssetAccount, "Muut varat"},
+	{LiabilityAccount, "Velat"},
+	{EquityAccount, "Oma pääoma"},
+	{cashFlowGroupOther, "Erittelemättä"},
+}
+
+// cashChange is how much an entry adds to cash if it is on a cash
+// account.
+func cashChange(entry DocumentEntry) int64 {
+	cents := entry.UnitCount * entry.UnitCostCents
+	if entry.IsDebit {
+		return cents
+	}
+	return -cents
+}
+
+func cashFlowRows(groups map[int]int64) ([]CashFlowRow, int64) {
+	rows := []CashFlowRow{}
+	var total int64
+	for _, g := range cashFlowGroups {
+		cents, ok := groups[g.group]
+		if !ok {
+			continue
+		}
+		rows = append(rows, CashFlowRow{
+			Title:       g.title,
+			Amount:      amountFromCents(cents),
+			AmountCents: cents,
+		})
+		total += cents
+	}
+	return rows, total
+}
+
+// GetCashFlow returns the cash on hand at the start and end of the
+// period, and the inflows and outflows between them grouped by the
+// type of the other accounts of each document that touches cash.
+// Moving money between cash accounts is neither. The dates of the
+// filter default to the latest period.
+func (m *Model) GetCashFlow(filter ReportFilter) CashFlow {
+	filter = m.StatementFilter(filter)
+	filter.ProjectID = ""
+	cf := CashFlow{Period: filter.String()}
+	if !m.isAdmin() {
+		return cf
+	}
+	acctMap := m.GetAccountMap()
+	isCash := func(acctID int) bool {
+		return acctMap[acctID].IsCash
+	}
+	for _, acct := range m.GetAccountList(false, "") {
+		if !acct.IsHeading() && acct.IsCash {
+			cf.CashAccounts = append(cf.CashAccounts, acct)
+		}
+	}
+	opening, _ := m.getOpeningBalances(acctMap, filter.StartDateFi)
+	for acctID, cents := range opening {
+		if isCash(acctID) {
+			cf.OpeningCashCents += cents
+		}
+	}
+	inflows := map[int]int64{}
+	outflows := map[int]int64{}
+	for _, document := range m.GetJournal(filter).Documents {
+		var cashCents int64
+		hasCash := false
+		groups := map[int]int64{}
+		for _, entry := range document.Entries {
+			if isCash(entry.AccountID) {
+				hasCash = true
+				cashCents += cashChange(entry)
+			}
+		}
+		if !hasCash {
+			continue
+		}
+		unexplained := cashCents
+		for _, entry := range document.Entries {
+			if isCash(entry.AccountID) {
+				continue
+			}
+			// Cash goes up by what the counter-accounts are credited.
+			group := cashFlowGroup(acctMap[entry.AccountID].AccountType)
+			groups[group] -= cashChange(entry)
+			unexplained += cashChange(entry)
+		}
+		if unexplained != 0 {
+			groups[cashFlowGroupOther] += unexplained
+		}
+		for group, cents := range groups {
+			if cents > 0 {
+				inflows[group] += cents
+			} else if cents < 0 {
+				outflows[group] -= cents
+			}
+		}
+	}
+	cf.Inflows, cf.TotalInflowCents = cashFlowRows(inflows)
+	cf.Outflows, cf.TotalOutflowCents = cashFlowRows(outflows)
+	cf.ClosingCashCents = cf.OpeningCashCents + cf.TotalInflowCents -
+		cf.TotalOutflowCents
+	cf.OpeningCash = amountFromCents(cf.OpeningCashCents)
+	cf.TotalInflow = amountFromCents(cf.TotalInflowCents)
+	cf.TotalOutflow = amountFromCents(cf.TotalOutflowCents)
+	cf.ClosingCash = amountFromCents(cf.ClosingCashCents)
+	return cf
+}
@@ -0,0 +1,124 @@
+package model
+
+import "testing"
+
+func TestCashFlowGroup(t *testing.T) {
+	tests := []struct {
+		acctType int
+		want     int
+	}{
+		{AssetAccount, AssetAccount},
+		{LiabilityAccount, LiabilityAccount},
+		{EquityAccount, EquityAccount},
+		{RevenueAccount, RevenueAccount},
+		{ExpenseAccount, ExpenseAccount},
+		{PastProfitAccount, EquityAccount},
+		{ProfitAccount, EquityAccount},
+	}
+	for _, test := range tests {
+		if got := cashFlowGroup(test.acctType); got != test.want {
+			t.Errorf("cashFlowGroup(%d) = %d, want %d",
+				test.acctType, got, test.want)
+		}
+	}
+}
+
+func TestGetCashFlow(t *testing.T) {
+	m := newTestModel(t)
+	postTestDocument(t, m, "5.3.2026", "12,50", "4000", "1910")
+	postTestDocument(t, m, "7.3.2026", "50,00", "1910", "3000")
+	// Moving money between cash accounts is neither in nor out.
+	postTestDocument(t, m, "1.4.2026", "100,00", "1900", "1910")
+	postTestDocument(t, m, "9.4.2026", "24,46", "4000", "1900")
+	postTestDocument(t, m, "10.4.2026", "200,00", "1910", "2510")
+	postTestDocument(t, m, "12.4.2026", "30,00", "1700", "1910")
+	tests := []struct {
+		filter   ReportFilter
+		opening  string
+		inflows  map[string]string
+		outflows map[string]string
+		closing  string
+	}{
+		{ReportFilter{}, "1000,00",
+			map[string]string{"Tulot": "50,00", "Velat": "200,00"},
+			map[string]string{"Menot": "36,96", "Muut varat": "30,00"},
+			"1183,04"},
+		{ReportFilter{StartDateFi: "1.4.2026", EndDateFi: "30.4.2026"},
+			"1037,50",
+			map[string]string{"Velat": "200,00"},
+			map[string]string{"Menot": "24,46", "Muut varat": "30,00"},
+			"1183,04"},
+		{ReportFilter{StartDateFi: "1.5.2026", EndDateFi: "31.5.2026"},
+			"1183,04", map[string]string{}, map[string]string{},
+			"1183,04"},
+	}
+	flowMap := func(rows []CashFlowRow) map[string]string {
+		flows := map[string]string{}
+		for _, row := range rows {
+			flows[row.Title] = row.Amount
+		}
+		return flows
+	}
+	sameFlows := func(got, want map[string]string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for title, amount := range want {
+			if got[title] != amount {
+				return false
+			}
+		}
+		return true
+	}
+	for i, test := range tests {
+		cf := m.GetCashFlow(test.filter)
+		if m.Err != nil {
+			t.Fatal(m.Err)
+		}
+		if len(cf.CashAccounts) != 2 {
+			t.Errorf("%d: cash accounts %v, want 1900 and 1910",
+				i, cf.CashAccounts)
+		}
+		if cf.OpeningCash != test.opening {
+			t.Errorf("%d: opening cash %q, want %q",
+				i, cf.OpeningCash, test.opening)
+		}
+		if got := flowMap(cf.Inflows); !sameFlows(got, test.inflows) {
+			t.Errorf("%d: inflows %v, want %v", i, got, test.inflows)
+		}
+		if got := flowMap(cf.Outflows); !sameFlows(got, test.outflows) {
+			t.Errorf("%d: outflows %v, want %v", i, got, test.outflows)
+		}
+		if cf.ClosingCash != test.closing {
+			t.Errorf("%d: closing cash %q, want %q",
+				i, cf.ClosingCash, test.closing)
+		}
+	}
+}
+
+func TestGetCashFlowSecondPeriod(t *testing.T) {
+	m := newTestModel(t)
+	postTestDocument(t, m, "5.3.2026", "12,50", "4000", "1910")
+	if _, err := m.tx.Exec(`
+insert into period values (2, '2027-01-01', '2027-12-31');
+insert into period_account
+ (period_id, account_id, account_type, title, nesting_level,
+  starting_balance_cents, is_cash)
+ select 2, account_id, account_type, title, nesting_level,
+  starting_balance_cents, is_cash
+ from period_account where period_id = 1;
+update period_account set starting_balance_cents = 98750
+ where period_id = 2 and account_id = 1910;
+`); err != nil {
+		t.Fatal(err)
+	}
+	postTestDocument(t, m, "1.2.2027", "50,00", "1910", "3000")
+	// The starting balances of 2027 already hold the cash spent in
+	// 2026.
+	cf := m.GetCashFlow(ReportFilter{})
+	if cf.OpeningCash != "987,50" || cf.TotalInflow != "50,00" ||
+		cf.ClosingCash != "1037,50" {
+		t.Errorf("cash %q + %q = %q, want 987,50 + 50,00 = 1037,50",
+			cf.OpeningCash, cf.TotalInflow, cf.ClosingCash)
+	}
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// DocumentProblems lists what is wrong with one document.
+type DocumentProblems struct {
+	DocumentID  string
+	PaidDateFi  string
+	Description string
+	Problems    []string
+}
+
+// DataQuality collects the documents that should be fixed before the
+// books are closed, and the document numbers that are skipped.
+type DataQuality struct {
+	Documents   []DocumentProblems
+	Gaps        []string
+	HasProblems bool
+}
+
+// formatGap describes the document numbers from first to last.
+func formatGap(first, last int) string {
+	if first == last {
+		return strconv.Itoa(first)
+	}
+	return fmt.Sprintf("%d–%d", first, last)
+}
+
+// isInPeriod tells whether the date is within one of the periods. A
+// period without an end date is still open.
+func isInPeriod(dateISO string, periods []Period) bool {
+	for _, p := range periods {
+		if dateISO >= p.StartDateISO &&
+			(p.EndDateISO == "" || dateISO <= p.EndDateISO) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDataQuality checks every document for a missing image, paid date
+// or account, a zero amount, debits that differ from credits and a
+// date outside all accounting periods.
+func (m *Model) GetDataQuality() DataQuality {
+	dq := DataQuality{}
+	if !m.isAdmin() {
+		return dq
+	}
+	_, missing := m.GetDocumentsForImages(ReportFilter{})
+	periods := m.GetPeriods(0)
+	rows, err := selectDocument().RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return dq
+	}
+	defer rows.Close()
+	var documents []Document
+	for rows.Next() {
+		document, err := scanDocument(rows)
+		if m.isErr(err) {
+			return dq
+		}
+		documents = append(documents, document)
+	}
+	if m.isErr(rows.Err()) {
+		return dq
+	}
+	noImage := map[string]bool{}
+	for _, documentID := range missing {
+		noImage[strconv.Itoa(documentID)] = true
+	}
+	var numbers []int
+	for _, document := range documents {
+		m.populateDocumentEntries(&document)
+		if m.Err != nil {
+			return dq
+		}
+		if number, err := strconv.Atoi(document.DocumentID); err == nil {
+			numbers = append(numbers, number)
+		}
+		var problems []string
+		if noImage[document.DocumentID] {
+			problems = append(problems, "Tositekuva puuttuu")
+		}
+		if document.PaidDateISO == "" {
+			problems = append(problems, "Maksupäivä puuttuu")
+		} else if len(periods) > 0 &&
+			!isInPeriod(document.PaidDateISO, periods) {
+			problems = append(problems,
+				"Päivämäärä ei ole millään tilikaudella")
+		}
+		var debitCents, creditCents int64
+		var hasDebit, hasCredit bool
+		for _, entry := range document.Entries {
+			cents := entry.UnitCount * entry.UnitCostCents
+			if entry.IsDebit {
+				hasDebit = true
+				debitCents += cents
+			} else {
+				hasCredit = true
+				creditCents += cents
+			}
+		}
+		if !hasDebit {
+			problems = append(problems, "Debet-tili puuttuu")
+		}
+		if !hasCredit {
+			problems = append(problems, "Kredit-tili puuttuu")
+		}
+		if debitCents == 0 && creditCents == 0 {
+			problems = append(problems, "Summa on nolla")
+		}
+		if debitCents != creditCents {
+			problems = append(problems,
+				"Debet ja kredit eivät täsmää, erotus "+
+					amountFromCents(debitCents-creditCents))
+		}
+		if len(problems) > 0 {
+			dq.Documents = append(dq.Documents, DocumentProblems{
+				DocumentID:  document.DocumentID,
+				PaidDateFi:  document.PaidDateFi,
+				Description: document.Description,
+				Problems:    problems,
+			})
+		}
+	}
+	sort.Ints(numbers)
+	for i := 1; i < len(numbers); i++ {
+		if numbers[i] > numbers[i-1]+1 {
+			dq.Gaps = append(dq.Gaps,
+				formatGap(numbers[i-1]+1, numbers[i]-1))
+		}
+	}
+	dq.HasProblems = len(dq.Documents) > 0 || len(dq.Gaps) > 0
+	return dq
+}
@@ -0,0 +1,28 @@
+package model
+
+import "testing"
+
+func TestIsInPeriod(t *testing.T) {
+	year2026 := Period{StartDateISO: "2026-01-01", EndDateISO: "2026-12-31"}
+	open2027 := Period{StartDateISO: "2027-01-01"}
+	tests := []struct {
+		date    string
+		periods []Period
+		want    bool
+	}{
+		{"2025-06-05", []Period{year2026}, false},
+		{"2025-12-31", []Period{year2026}, false},
+		{"2026-01-01", []Period{year2026}, true},
+		{"2026-12-31", []Period{year2026}, true},
+		{"2027-01-01", []Period{year2026}, false},
+		{"2027-03-01", []Period{open2027, year2026}, true},
+		{"2025-06-05", []Period{open2027, year2026}, false},
+		{"2026-06-05", nil, false},
+	}
+	for _, test := range tests {
+		if got := isInPeriod(test.date, test.periods); got != test.want {
+			t.Errorf("isInPeriod(%q, %v) = %v, want %v",
+				test.date, test.periods, got, test.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
@@ -10,6 +11,19 @@ import (
 	sq "github.com/Masterminds/squirrel"
 )
 
+const (
+	DocumentApproved = "approved"
+	DocumentClosed   = "closed"
+)
+
+const (
+	DocumentFilterAll    = ""
+	DocumentFilterOpen   = "open"
+	DocumentFilterClosed = "closed"
+)
+
+var ErrDocumentClosed = errors.New("Document is closed")
+
 type DocumentEntry struct {
 	RowNumber     int
 	AccountID     int
@@ -18,6 +32,7 @@ type DocumentEntry struct {
 	UnitCostCents int64
 	Amount        string
 	Description   string
+	ProjectID     string
 }
 
 type Document struct {
@@ -32,9 +47,16 @@ type Document struct {
 	PaidUser        User
 	CreditAccountID string
 	DebitAccountID  string
+	ProjectID       string
 	ImageID         string
 	Amount          string
 	AmountCents     int64
+	ClosedType      string
+	ClosedDateFi    string
+	ClosedUser      User
+	IsClosed        bool
+	IsApproved      bool
+	Reimbursement   Reimbursement
 	Images          []map[string]string
 	Entries         []DocumentEntry
 }
@@ -54,11 +76,20 @@ func withCents(document sq.SelectBuilder) sq.SelectBuilder {
 	return document.Column(fmt.Sprintf("max((%s), (%s)) as cents", debit, credit))
 }
 
+func withClosed(document sq.SelectBuilder) sq.SelectBuilder {
+	return document.LeftJoin(
+		"user as closed_user on (closed_user.user_id = document.closed_user_id)").
+		Columns("closed_type", "closed_date", "closed_user_id",
+			"closed_user.full_name as closed_user_full_name")
+}
+
 func selectDocument() sq.SelectBuilder {
 	q := sq.Select("document_id, description, paid_date").
 		From("document").OrderBy("document_id, description")
 	q = withPaidUser(q)
 	q = withCents(q)
+	q = withClosed(q)
+	q = withReimbursement(q)
 	return q
 }
 
@@ -69,10 +100,26 @@ func scanDocument(rows sq.RowScanner) (Document, error) {
 	var paidUserID sql.NullInt64
 	var paidUserFullName sql.NullString
 	var cents sql.NullInt64
+	var closedType sql.NullString
+	var closedDateISO sql.NullString
+	var closedUserID sql.NullInt64
+	var closedUserFullName sql.NullString
+	var reimbursementState sql.NullString
+	var reimbursedDateISO sql.NullString
 	if err := rows.Scan(&b.DocumentID, &description, &paidDateISO,
-		&paidUserID, &paidUserFullName, &cents); err != nil {
+		&paidUserID, &paidUserFullName, &cents, &closedType,
+		&closedDateISO, &closedUserID, &closedUserFullName,
+		&reimbursementState, &reimbursedDateISO); err != nil {
 		return b, err
 	}
+	b.Reimbursement = makeReimbursement(reimbursementState.String,
+		reimbursedDateISO.String)
+	b.ClosedType = closedType.String
+	b.ClosedDateFi = fiFromISODate(closedDateISO.String)
+	b.ClosedUser.UserID = closedUserID.Int64
+	b.ClosedUser.FullName = closedUserFullName.String
+	b.IsClosed = (b.ClosedType != "")
+	b.IsApproved = (b.ClosedType == DocumentApproved)
 	b.Description = description.String
 	b.PaidDateISO = paidDateISO.String
 	b.PaidDateFi = fiFromISODate(b.PaidDateISO)
@@ -83,13 +130,19 @@ func scanDocument(rows sq.RowScanner) (Document, error) {
 	return b, nil
 }
 
-func (m *Model) GetDocuments() []Document {
+func (m *Model) GetDocuments(filter string) []Document {
 	noDocuments := []Document{}
 	documents := noDocuments
 	q := selectDocument()
 	if !m.user.IsAdmin {
 		q = q.Where(sq.Eq{"paid_user_id": m.user.UserID})
 	}
+	switch filter {
+	case DocumentFilterOpen:
+		q = q.Where(sq.Eq{"closed_type": nil})
+	case DocumentFilterClosed:
+		q = q.Where(sq.NotEq{"closed_type": nil})
+	}
 	rows, err := q.RunWith(m.tx).Query()
 	if m.isErr(err) {
 		return noDocuments
@@ -108,16 +161,28 @@ func (m *Model) GetDocuments() []Document {
 	return documents
 }
 
-func (m *Model) GetDocumentsForImages() ([]map[string]interface{}, []int) {
-	var images []map[string]interface{}
+// DocumentImageFile names one image of a document without its data,
+// which is fetched with GetImage when needed.
+type DocumentImageFile struct {
+	DocumentID       int
+	DocumentImageNum int
+	ImageID          string
+	Description      string
+}
+
+// GetDocumentsForImages lists the images of the documents that the
+// dates and document numbers of the filter pick, and the numbers of
+// those documents that have none.
+func (m *Model) GetDocumentsForImages(filter ReportFilter) ([]DocumentImageFile, []int) {
+	var images []DocumentImageFile
 	var missing []int
 	if !m.isAdmin() {
 		return images, missing
 	}
-	rows, err := sq.Select("document.document_id, document_image_num, image.image_id, description, image_data").
-		From("document").
-		LeftJoin("document_image on document_id = document_id").
-		LeftJoin("image on image_id = image_id").
+	rows, err := filter.whereDocument(
+		sq.Select("document.document_id, document_image_num, image_id, description").
+			From("document").
+			LeftJoin("document_image on document_image.document_id = document.document_id")).
 		OrderBy("document.document_id, document_image_num").
 		RunWith(m.tx).Query()
 	if m.isErr(err) {
@@ -125,22 +190,20 @@ func (m *Model) GetDocumentsForImages() ([]map[string]interface{}, []int) {
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var document_id string
-		var document_image_num int
-		var image_id string
-		var description string
-		var image_data []byte
-		if m.isErr(rows.Scan(&document_id, &document_image_num, &image_id,
-			&description, &image_data)) {
+		var image DocumentImageFile
+		var imageNum sql.NullInt64
+		var imageID sql.NullString
+		if m.isErr(rows.Scan(&image.DocumentID, &imageNum, &imageID,
+			&image.Description)) {
 			return images, missing
 		}
-		images = append(images, map[string]interface{}{
-			"document_id":        document_id,
-			"document_image_num": document_image_num,
-			"image_id":           image_id,
-			"description":        description,
-			"image_data":         image_data,
-		})
+		if !imageID.Valid {
+			missing = append(missing, image.DocumentID)
+			continue
+		}
+		image.DocumentImageNum = int(imageNum.Int64)
+		image.ImageID = imageID.String
+		images = append(images, image)
 	}
 	m.isErr(rows.Err())
 	return images, missing
@@ -206,15 +269,18 @@ func (m *Model) GetDocumentID(documentID string) *Document {
 }
 
 func selectDocumentEntry() sq.SelectBuilder {
-	return sq.Select("row_number, account_id, debit, unit_count, unit_cost_cents, description").
+	return sq.Select("row_number, account_id, debit, unit_count, unit_cost_cents, description, project_id").
 		From("document_entry").
 		OrderBy("document_id, row_number")
 }
 
 func scanDocumentEntry(rows sq.RowScanner) (DocumentEntry, error) {
 	e := DocumentEntry{}
+	var projectID sql.NullString
 	err := rows.Scan(&e.RowNumber, &e.AccountID,
-		&e.IsDebit, &e.UnitCount, &e.UnitCostCents, &e.Description)
+		&e.IsDebit, &e.UnitCount, &e.UnitCostCents, &e.Description,
+		&projectID)
+	e.ProjectID = projectID.String
 	e.Amount = amountFromCents(e.UnitCount * e.UnitCostCents)
 	return e, err
 }
@@ -244,6 +310,14 @@ func (m *Model) GetAllDocumentEntries() []DocumentEntry {
 	return m.documentEntriesFromSelect(selectDocumentEntry())
 }
 
+// GetPeriodDocumentEntries returns the entries of the documents paid
+// within the given period.
+func (m *Model) GetPeriodDocumentEntries(period Period) []DocumentEntry {
+	return m.documentEntriesFromSelect(selectDocumentEntry().
+		Where("document_id in (select document_id from document where paid_date between ? and ?)",
+			period.StartDateISO, period.EndDateISO))
+}
+
 func (m *Model) populateDocumentEntries(document *Document) {
 	document.Entries = m.documentEntriesFromSelect(
 		selectDocumentEntry().Where(sq.Eq{"document_id": document.DocumentID}))
@@ -270,6 +344,20 @@ func (m *Model) populateDocumentEntriesFromOtherDocumentFields(document *Documen
 	}
 	addEntry(document.CreditAccountID, "Credit", false)
 	addEntry(document.DebitAccountID, "Debet", true)
+	if document.ProjectID != "" && len(entries) > 0 {
+		// The project goes on the revenue or expense, not on the bank
+		// or other balance sheet account that pays for it. If there is
+		// neither, the last entry keeps it so that it is not lost.
+		acctMap := m.GetAccountMap()
+		tagged := len(entries) - 1
+		for i, entry := range entries {
+			switch acctMap[entry.AccountID].AccountType {
+			case RevenueAccount, ExpenseAccount:
+				tagged = i
+			}
+		}
+		entries[tagged].ProjectID = document.ProjectID
+	}
 	document.Entries = entries
 }
 
@@ -281,9 +369,45 @@ func (m *Model) populateOtherDocumentFieldsFromDocumentEntries(document *Documen
 		QueryRow().Scan(&document.CreditAccountID)
 	q.Where("debit = 1").RunWith(m.tx).Limit(1).
 		QueryRow().Scan(&document.DebitAccountID)
+	sq.Select("project_id").From("document_entry").
+		Where(sq.Eq{"document_id": document.DocumentID}).
+		Where("project_id is not null").RunWith(m.tx).Limit(1).
+		QueryRow().Scan(&document.ProjectID)
+}
+
+func (entry DocumentEntry) auditString() string {
+	side := "Kredit"
+	if entry.IsDebit {
+		side = "Debet"
+	}
+	s := fmt.Sprintf("%d %s %s %s", entry.AccountID, side,
+		amountFromCents(entry.UnitCount*entry.UnitCostCents),
+		entry.Description)
+	if entry.ProjectID != "" {
+		s += " (projekti " + entry.ProjectID + ")"
+	}
+	return s
+}
+
+func (m *Model) auditDocumentEntries(documentID string,
+	oldEntries, newEntries []DocumentEntry) {
+	for i := 0; i < len(oldEntries) || i < len(newEntries); i++ {
+		oldValue := ""
+		newValue := ""
+		if i < len(oldEntries) {
+			oldValue = oldEntries[i].auditString()
+		}
+		if i < len(newEntries) {
+			newValue = newEntries[i].auditString()
+		}
+		m.audit(AuditDocumentEntry, documentID,
+			fmt.Sprintf("rivi %d", i+1), oldValue, newValue)
+	}
 }
 
 func (m *Model) putDocumentEntries(document Document) {
+	oldEntries := m.documentEntriesFromSelect(
+		selectDocumentEntry().Where(sq.Eq{"document_id": document.DocumentID}))
 	_, err := sq.Delete("document_entry").Where(sq.Eq{"document_id": document.DocumentID}).
 		RunWith(m.tx).Exec()
 	if m.isErr(err) {
@@ -293,6 +417,10 @@ func (m *Model) putDocumentEntries(document Document) {
 		if entry.RowNumber != rowNumber {
 			panic("Row number mismatch")
 		}
+		var projectID interface{}
+		if entry.ProjectID != "" {
+			projectID = entry.ProjectID
+		}
 		_, err := sq.Insert("document_entry").SetMap(sq.Eq{
 			"document_id":     document.DocumentID,
 			"row_number":      rowNumber,
@@ -301,14 +429,22 @@ func (m *Model) putDocumentEntries(document Document) {
 			"account_id":      entry.AccountID,
 			"debit":           entry.IsDebit,
 			"description":     entry.Description,
+			"project_id":      projectID,
 		}).RunWith(m.tx).Exec()
 		if m.isErr(err) {
 			return
 		}
 	}
+	m.auditDocumentEntries(document.DocumentID, oldEntries, document.Entries)
 }
 
 func (m *Model) putDocumentImages(document Document) {
+	oldImageID := ""
+	if oldImages := m.getDocumentImages(document.DocumentID); len(oldImages) > 0 {
+		oldImageID = oldImages[0]["ImageID"]
+	}
+	m.audit(AuditDocumentImage, document.DocumentID, "kuva",
+		oldImageID, document.ImageID)
 	_, err := sq.Delete("document_image").Where(sq.Eq{"document_id": document.DocumentID}).
 		RunWith(m.tx).Exec()
 	if m.isErr(err) {
@@ -340,20 +476,40 @@ func (m *Model) PutDocument(document Document) {
 		panic("Non-null PaidUser.UserID for non-admin in PutDocument")
 	}
 	var oldPaidUserID sql.NullInt64
-	if m.isErr(sq.Select("paid_user_id").
+	var oldDescription string
+	var oldPaidDate sql.NullString
+	var closedType sql.NullString
+	if m.isErr(sq.Select("paid_user_id, description, paid_date, closed_type").
 		From("document").Where(sq.Eq{"document_id": documentID}).
-		RunWith(m.tx).QueryRow().Scan(&oldPaidUserID)) {
+		RunWith(m.tx).QueryRow().Scan(&oldPaidUserID, &oldDescription,
+		&oldPaidDate, &closedType)) {
 		return
 	}
 	if !m.isAdminOrUser(oldPaidUserID.Int64) {
 		return
 	}
+	if closedType.String != "" {
+		m.isErr(ErrDocumentClosed)
+		return
+	}
+	m.audit(AuditDocument, document.DocumentID, "description",
+		oldDescription, document.Description)
+	m.audit(AuditDocument, document.DocumentID, "paid_date",
+		oldPaidDate.String, setmap["paid_date"].(string))
 	if m.user.IsAdmin {
 		if document.PaidUser.UserID == 0 {
 			setmap["paid_user_id"] = nil
+			setmap["reimbursement_state"] = nil
+			setmap["reimbursed_date"] = nil
 		} else {
 			setmap["paid_user_id"] = document.PaidUser.UserID
+			setmap["reimbursement_state"] = sq.Expr(
+				"coalesce(reimbursement_state, ?)",
+				ReimbursementSubmitted)
 		}
+		m.audit(AuditDocument, document.DocumentID, "paid_user_id",
+			auditUserID(oldPaidUserID.Int64),
+			auditUserID(document.PaidUser.UserID))
 		m.populateDocumentEntriesFromOtherDocumentFields(&document)
 		m.putDocumentEntries(document)
 	}
@@ -367,6 +523,50 @@ func (m *Model) PutDocument(document Document) {
 	}
 }
 
+// CloseDocument marks the document approved or closed. After that it
+// can no longer be edited until an admin reopens it.
+func (m *Model) CloseDocument(documentID string, closedType string) {
+	if !m.isAdmin() {
+		return
+	}
+	if closedType != DocumentApproved && closedType != DocumentClosed {
+		m.isErr(fmt.Errorf("Invalid closed type: %q", closedType))
+		return
+	}
+	m.setDocumentClosed(documentID, sq.Eq{
+		"closed_type":    closedType,
+		"closed_date":    time.Now().Format("2006-01-02"),
+		"closed_user_id": userIDOrNil(m.user.UserID),
+	})
+}
+
+// ReopenDocument makes a closed document editable again.
+func (m *Model) ReopenDocument(documentID string) {
+	if !m.isAdmin() {
+		return
+	}
+	m.setDocumentClosed(documentID, sq.Eq{
+		"closed_type":    nil,
+		"closed_date":    nil,
+		"closed_user_id": nil,
+	})
+}
+
+func (m *Model) setDocumentClosed(documentID string, setmap sq.Eq) {
+	var oldClosedType sql.NullString
+	if m.isErr(sq.Select("closed_type").From("document").
+		Where(sq.Eq{"document_id": documentID}).
+		RunWith(m.tx).QueryRow().Scan(&oldClosedType)) {
+		return
+	}
+	newClosedType, _ := setmap["closed_type"].(string)
+	m.audit(AuditDocument, documentID, "closed_type",
+		oldClosedType.String, newClosedType)
+	_, err := sq.Update("document").SetMap(setmap).
+		Where(sq.Eq{"document_id": documentID}).RunWith(m.tx).Exec()
+	m.isErr(err)
+}
+
 func (m *Model) getNewDocumentID() (documentID int64, err error) {
 	err = sq.Select("coalesce(max(document_id), 0) + 1").From("document").
 		RunWith(m.tx).Limit(1).QueryRow().Scan(&documentID)
@@ -382,6 +582,7 @@ func (m *Model) PostDocument(document Document) string {
 	setMap := sq.Eq{"document_id": documentID, "created_date": createdDate}
 	if !m.user.IsAdmin {
 		setMap["paid_user_id"] = m.user.UserID
+		setMap["reimbursement_state"] = ReimbursementSubmitted
 	}
 	_, err = sq.Insert("document").SetMap(setMap).RunWith(m.tx).Exec()
 	if m.isErr(err) {
@@ -389,6 +590,8 @@ func (m *Model) PostDocument(document Document) string {
 	}
 	document.DocumentID = strconv.Itoa(int(documentID))
 	log.Printf("Created document #%s", document.DocumentID)
+	m.audit(AuditDocument, document.DocumentID, "created_date",
+		"", createdDate)
 	m.PutDocument(document)
 	return document.DocumentID
 }
@@ -8,12 +8,14 @@ type Journal struct {
 	TotalCreditCents int64
 }
 
-func (m *Model) GetJournal() Journal {
+// GetJournal returns the documents that pass the filter, with only
+// the entries that pass it.
+func (m *Model) GetJournal(filter ReportFilter) Journal {
 	journal := Journal{}
 	if !m.isAdmin() {
 		return journal
 	}
-	q := selectDocument()
+	q := filter.whereDocument(selectDocument())
 	rows, err := q.RunWith(m.tx).Query()
 	if m.isErr(err) {
 		return journal
@@ -28,6 +30,12 @@ func (m *Model) GetJournal() Journal {
 			return journal
 		}
 		m.populateDocumentEntries(&document)
+		if filter.filtersEntries() {
+			document.Entries = filter.filterEntries(document.Entries)
+			if len(document.Entries) == 0 {
+				continue
+			}
+		}
 		for _, documentEntry := range document.Entries {
 			cents := documentEntry.UnitCount * documentEntry.UnitCostCents
 			if documentEntry.IsDebit {
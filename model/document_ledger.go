@@ -2,6 +2,7 @@ package model
 
 import (
 	"sort"
+	"strconv"
 )
 
 type LedgerEntry struct {
@@ -12,6 +13,8 @@ type LedgerEntry struct {
 	CreditAmount string
 	BalanceAfter string
 	Description  string
+	// The change to the balance, debits positive.
+	deltaCents int64
 }
 
 type LedgerAccount struct {
@@ -28,13 +31,50 @@ type Ledger struct {
 	TotalCreditCents int64
 }
 
-func (m *Model) GetLedger() Ledger {
+// ledgerOpeningBalances returns the balances of the accounts at the
+// start date of the filter with debits positive, as the ledger shows
+// them. Starting balances are not kept per project, so a ledger of one
+// project starts from zero.
+func (m *Model) ledgerOpeningBalances(acctMap map[int]Account,
+	filter ReportFilter) map[int]int64 {
+	balances := map[int]int64{}
+	if filter.ProjectID != "" {
+		return balances
+	}
+	if filter.StartDateFi != "" {
+		balances, _ = m.getOpeningBalances(acctMap, filter.StartDateFi)
+	} else if periods := m.GetPeriods(0); len(periods) > 0 {
+		balances = m.getStartingBalances(periods[len(periods)-1].PeriodID)
+	}
+	for acctID, cents := range balances {
+		if entrySubtractsFromBalance(true, acctMap[acctID].AccountType) {
+			balances[acctID] = -cents
+		}
+	}
+	return balances
+}
+
+// documentNumber orders documents numerically, falling back to zero
+// for a document ID that is not a number.
+func documentNumber(documentID string) int {
+	number, _ := strconv.Atoi(documentID)
+	return number
+}
+
+// GetLedger returns the entries of each account that pass the
+// filter, by date and document number, with the balance after each
+// entry starting from the opening balance at the start date.
+func (m *Model) GetLedger(filter ReportFilter) Ledger {
 	ledger := Ledger{}
 	if !m.isAdmin() {
 		return ledger
 	}
 	acctMap := m.GetAccountMap()
-	rows, err := selectDocument().RunWith(m.tx).Query()
+	opening := m.ledgerOpeningBalances(acctMap, filter)
+	if m.Err != nil {
+		return ledger
+	}
+	rows, err := filter.whereDocument(selectDocument()).RunWith(m.tx).Query()
 	if m.isErr(err) {
 		return ledger
 	}
@@ -48,48 +88,51 @@ func (m *Model) GetLedger() Ledger {
 			return ledger
 		}
 		m.populateDocumentEntries(&document)
-		for _, documentEntry := range document.Entries {
+		for _, documentEntry := range filter.filterEntries(document.Entries) {
 			cents := documentEntry.UnitCount * documentEntry.UnitCostCents
-			ledgerAccount := ledgerMap[documentEntry.AccountID]
-			ledgerAccount.AccountID = documentEntry.AccountID
-			ledgerAccount.AccountTitle =
-				acctMap[ledgerAccount.AccountID].Title
+			acctID := documentEntry.AccountID
+			ledgerAccount := ledgerMap[acctID]
+			ledgerAccount.AccountID = acctID
+			ledgerAccount.AccountTitle = acctMap[acctID].Title
 			ledgerEntry := LedgerEntry{}
 			if documentEntry.IsDebit {
 				ledgerEntry.DebitAmount = documentEntry.Amount
-				ledgerAccount.CurrentBalanceCents += cents
+				ledgerEntry.deltaCents = cents
 				totalDebitCents += cents
 			} else {
 				ledgerEntry.CreditAmount = documentEntry.Amount
-				ledgerAccount.CurrentBalanceCents -= cents
+				ledgerEntry.deltaCents = -cents
 				totalCreditCents += cents
 			}
-			ledgerEntry.BalanceAfter = amountFromCents(
-				ledgerAccount.CurrentBalanceCents)
 			ledgerEntry.DocumentID = document.DocumentID
 			ledgerEntry.PaidDateISO = document.PaidDateISO
 			ledgerEntry.PaidDateFi = document.PaidDateFi
 			ledgerEntry.Description = documentEntry.Description
 			ledgerAccount.Entries =
 				append(ledgerAccount.Entries, ledgerEntry)
-			ledgerMap[documentEntry.AccountID] = ledgerAccount
+			ledgerMap[acctID] = ledgerAccount
 		}
 	}
 	if m.isErr(rows.Err()) {
 		return ledger
 	}
 	acctList := []LedgerAccount{}
-	for _, ledgerAccount := range ledgerMap {
+	for acctID, ledgerAccount := range ledgerMap {
 		ents := ledgerAccount.Entries
-		sort.Slice(ents, func(i, j int) bool {
-			if ents[i].PaidDateISO < ents[j].PaidDateISO {
-				return true
-			}
-			if ents[i].PaidDateISO > ents[j].PaidDateISO {
-				return false
+		sort.SliceStable(ents, func(i, j int) bool {
+			if ents[i].PaidDateISO != ents[j].PaidDateISO {
+				return ents[i].PaidDateISO < ents[j].PaidDateISO
 			}
-			return ents[i].DocumentID < ents[j].DocumentID
+			return documentNumber(ents[i].DocumentID) <
+				documentNumber(ents[j].DocumentID)
 		})
+		balance := opening[acctID]
+		ledgerAccount.StartingBalance = amountFromCents(balance)
+		for i := range ents {
+			balance += ents[i].deltaCents
+			ents[i].BalanceAfter = amountFromCents(balance)
+		}
+		ledgerAccount.CurrentBalanceCents = balance
 		acctList = append(acctList, ledgerAccount)
 	}
 	sort.Slice(acctList, func(i, j int) bool {
@@ -0,0 +1,219 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var ErrInvoicePosted = errors.New("Invoice is already posted")
+
+type Invoice struct {
+	InvoiceID           string
+	CustomerName        string
+	CustomerAddress     string
+	Description         string
+	InvoiceDateISO      string
+	InvoiceDateFi       string
+	DueDateISO          string
+	DueDateFi           string
+	Amount              string
+	AmountCents         int64
+	ReferenceNumber     string
+	ReceivableAccountID string
+	RevenueAccountID    string
+	DocumentID          string
+	IsPosted            bool
+	Paid                string
+	PaymentStatus       string
+	IsPaid              bool
+}
+
+func selectInvoice() sq.SelectBuilder {
+	return sq.Select("invoice.invoice_id, customer_name, customer_address, invoice.description, invoice_date, invoice.due_date, invoice.amount_cents, invoice.reference_number, invoice.receivable_account_id, revenue_account_id, document_id, coalesce(expected_payment.paid_cents, 0)").
+		From("invoice").
+		LeftJoin("expected_payment on (expected_payment.invoice_id = invoice.invoice_id)").
+		OrderBy("invoice.invoice_id")
+}
+
+func scanInvoice(rows sq.RowScanner) (Invoice, error) {
+	var inv Invoice
+	var documentID sql.NullString
+	var paidCents int64
+	err := rows.Scan(&inv.InvoiceID, &inv.CustomerName,
+		&inv.CustomerAddress, &inv.Description, &inv.InvoiceDateISO,
+		&inv.DueDateISO, &inv.AmountCents, &inv.ReferenceNumber,
+		&inv.ReceivableAccountID, &inv.RevenueAccountID, &documentID,
+		&paidCents)
+	inv.Paid = amountFromCents(paidCents)
+	inv.PaymentStatus = paymentStatus(inv.AmountCents, paidCents)
+	inv.IsPaid = (inv.PaymentStatus == PaymentPaid)
+	inv.InvoiceDateFi = fiFromISODate(inv.InvoiceDateISO)
+	inv.DueDateFi = fiFromISODate(inv.DueDateISO)
+	inv.Amount = amountFromCents(inv.AmountCents)
+	inv.DocumentID = documentID.String
+	inv.IsPosted = (inv.DocumentID != "")
+	return inv, err
+}
+
+func (m *Model) invoicesFromSelect(q sq.SelectBuilder) []Invoice {
+	noInvoices := []Invoice{}
+	invoices := noInvoices
+	rows, err := q.RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return noInvoices
+	}
+	defer rows.Close()
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if m.isErr(err) {
+			return noInvoices
+		}
+		invoices = append(invoices, inv)
+	}
+	if m.isErr(rows.Err()) {
+		return noInvoices
+	}
+	return invoices
+}
+
+func (m *Model) GetInvoices() []Invoice {
+	if !m.isAdmin() {
+		return []Invoice{}
+	}
+	return m.invoicesFromSelect(selectInvoice())
+}
+
+func (m *Model) GetInvoice(invoiceID string) *Invoice {
+	if !m.isAdmin() {
+		return nil
+	}
+	inv, err := scanInvoice(selectInvoice().
+		Where(sq.Eq{"invoice.invoice_id": invoiceID}).RunWith(m.tx).QueryRow())
+	if err != nil {
+		return nil
+	}
+	return &inv
+}
+
+func (m *Model) invoiceSetMap(inv Invoice) sq.Eq {
+	amountCents, err := centsFromAmount(inv.Amount)
+	if m.isErr(err) {
+		return nil
+	}
+	invoiceDate := isoFromFiDate(inv.InvoiceDateFi)
+	dueDate := isoFromFiDate(inv.DueDateFi)
+	if invoiceDate == "" || dueDate == "" {
+		m.isErr(fmt.Errorf("Invalid invoice dates: %q, %q",
+			inv.InvoiceDateFi, inv.DueDateFi))
+		return nil
+	}
+	receivableAccountID := parsePositiveInt("account ID",
+		inv.ReceivableAccountID)
+	revenueAccountID := parsePositiveInt("account ID",
+		inv.RevenueAccountID)
+	if receivableAccountID < 1 || revenueAccountID < 1 {
+		m.isErr(fmt.Errorf("Invoice accounts missing"))
+		return nil
+	}
+	return sq.Eq{
+		"customer_name":         inv.CustomerName,
+		"customer_address":      inv.CustomerAddress,
+		"description":           inv.Description,
+		"invoice_date":          invoiceDate,
+		"due_date":              dueDate,
+		"amount_cents":          amountCents,
+		"receivable_account_id": receivableAccountID,
+		"revenue_account_id":    revenueAccountID,
+	}
+}
+
+// PostInvoice creates a new outgoing invoice and gives it a reference
+// number. The invoice is not yet in the books.
+func (m *Model) PostInvoice(inv Invoice) string {
+	if !m.isAdmin() {
+		return ""
+	}
+	setmap := m.invoiceSetMap(inv)
+	if setmap == nil {
+		return ""
+	}
+	var invoiceID int64
+	if m.isErr(sq.Select("coalesce(max(invoice_id), 0) + 1").
+		From("invoice").RunWith(m.tx).Limit(1).QueryRow().
+		Scan(&invoiceID)) {
+		return ""
+	}
+	setmap["invoice_id"] = invoiceID
+	setmap["reference_number"] = referenceFor(invoiceReferencePrefix,
+		invoiceID)
+	_, err := sq.Insert("invoice").SetMap(setmap).RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return ""
+	}
+	return strconv.FormatInt(invoiceID, 10)
+}
+
+// PutInvoice changes an invoice that has not yet been posted.
+func (m *Model) PutInvoice(inv Invoice) {
+	old := m.GetInvoice(inv.InvoiceID)
+	if old == nil {
+		m.isErr(fmt.Errorf("No such invoice: %q", inv.InvoiceID))
+		return
+	}
+	if old.IsPosted {
+		m.isErr(ErrInvoicePosted)
+		return
+	}
+	setmap := m.invoiceSetMap(inv)
+	if setmap == nil {
+		return
+	}
+	_, err := sq.Update("invoice").SetMap(setmap).
+		Where(sq.Eq{"invoice_id": inv.InvoiceID}).RunWith(m.tx).Exec()
+	m.isErr(err)
+}
+
+// PostInvoiceToBooks creates the document that debits the receivable
+// and credits the revenue account of the invoice, and starts waiting
+// for the payment.
+func (m *Model) PostInvoiceToBooks(invoiceID string) string {
+	inv := m.GetInvoice(invoiceID)
+	if inv == nil {
+		m.isErr(fmt.Errorf("No such invoice: %q", invoiceID))
+		return ""
+	}
+	if inv.IsPosted {
+		return inv.DocumentID
+	}
+	documentID := m.PostDocument(Document{
+		PaidDateFi: inv.InvoiceDateFi,
+		Description: fmt.Sprintf("Lasku %s, %s: %s (viite %s)",
+			inv.InvoiceID, inv.CustomerName, inv.Description,
+			inv.ReferenceNumber),
+		Amount:          inv.Amount,
+		DebitAccountID:  inv.ReceivableAccountID,
+		CreditAccountID: inv.RevenueAccountID,
+	})
+	if m.Err != nil {
+		return ""
+	}
+	_, err := sq.Update("invoice").Set("document_id", documentID).
+		Where(sq.Eq{"invoice_id": invoiceID}).RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return ""
+	}
+	m.PostExpectedPayment(ExpectedPayment{
+		ReferenceNumber: inv.ReferenceNumber,
+		Description: fmt.Sprintf("Lasku %s, %s",
+			inv.InvoiceID, inv.CustomerName),
+		Amount:              inv.Amount,
+		DueDateFi:           inv.DueDateFi,
+		ReceivableAccountID: inv.ReceivableAccountID,
+		InvoiceID:           inv.InvoiceID,
+	})
+	return documentID
+}
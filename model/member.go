@@ -0,0 +1,313 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type Member struct {
+	MemberID       string
+	FullName       string
+	Email          string
+	Phone          string
+	Address        string
+	MembershipType string
+	JoinDateFi     string
+	LeaveDateFi    string
+	IsActive       bool
+}
+
+// MemberFee is the membership fee charged from a member for one
+// accounting period. It is paid either through the reference number
+// of its expected payment or by linking it to a document.
+type MemberFee struct {
+	Member          Member
+	PeriodID        int64
+	Period          string
+	Amount          string
+	AmountCents     int64
+	Paid            string
+	PaidCents       int64
+	ReferenceNumber string
+	DocumentID      string
+	IsPaid          bool
+}
+
+func selectMember() sq.SelectBuilder {
+	return sq.Select("member.member_id, full_name, email, phone, address, membership_type, join_date, leave_date").
+		From("member").
+		OrderBy("lower(full_name), member.member_id")
+}
+
+func scanMember(rows sq.RowScanner, extra ...interface{}) (Member, error) {
+	var mb Member
+	var joinDate sql.NullString
+	var leaveDate sql.NullString
+	dest := append([]interface{}{&mb.MemberID, &mb.FullName, &mb.Email,
+		&mb.Phone, &mb.Address, &mb.MembershipType, &joinDate,
+		&leaveDate}, extra...)
+	err := rows.Scan(dest...)
+	mb.JoinDateFi = fiFromISODate(joinDate.String)
+	mb.LeaveDateFi = fiFromISODate(leaveDate.String)
+	mb.IsActive = (mb.LeaveDateFi == "")
+	return mb, err
+}
+
+func (m *Model) GetMembers() []Member {
+	noMembers := []Member{}
+	if !m.isAdmin() {
+		return noMembers
+	}
+	rows, err := selectMember().RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return noMembers
+	}
+	defer rows.Close()
+	members := noMembers
+	for rows.Next() {
+		mb, err := scanMember(rows)
+		if m.isErr(err) {
+			return noMembers
+		}
+		members = append(members, mb)
+	}
+	if m.isErr(rows.Err()) {
+		return noMembers
+	}
+	return members
+}
+
+func (m *Model) GetMember(memberID string) *Member {
+	if !m.isAdmin() {
+		return nil
+	}
+	mb, err := scanMember(selectMember().
+		Where(sq.Eq{"member.member_id": memberID}).RunWith(m.tx).QueryRow())
+	if err != nil {
+		return nil
+	}
+	return &mb
+}
+
+func memberSetMap(mb Member) sq.Eq {
+	dateOrNil := func(fi string) interface{} {
+		if iso := isoFromFiDate(fi); iso != "" {
+			return iso
+		}
+		return nil
+	}
+	return sq.Eq{
+		"full_name":       mb.FullName,
+		"email":           mb.Email,
+		"phone":           mb.Phone,
+		"address":         mb.Address,
+		"membership_type": mb.MembershipType,
+		"join_date":       dateOrNil(mb.JoinDateFi),
+		"leave_date":      dateOrNil(mb.LeaveDateFi),
+	}
+}
+
+func (m *Model) PostMember(mb Member) string {
+	if !m.isAdmin() {
+		return ""
+	}
+	var memberID int64
+	if m.isErr(sq.Select("coalesce(max(member_id), 0) + 1").
+		From("member").RunWith(m.tx).Limit(1).QueryRow().
+		Scan(&memberID)) {
+		return ""
+	}
+	setmap := memberSetMap(mb)
+	setmap["member_id"] = memberID
+	_, err := sq.Insert("member").SetMap(setmap).RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return ""
+	}
+	return strconv.FormatInt(memberID, 10)
+}
+
+func (m *Model) PutMember(mb Member) {
+	if !m.isAdmin() {
+		return
+	}
+	_, err := sq.Update("member").SetMap(memberSetMap(mb)).
+		Where(sq.Eq{"member_id": mb.MemberID}).RunWith(m.tx).Exec()
+	m.isErr(err)
+}
+
+func selectMemberFee() sq.SelectBuilder {
+	return selectMember().
+		Join("member_fee on (member_fee.member_id = member.member_id)").
+		Join("period on (period.period_id = member_fee.period_id)").
+		LeftJoin("expected_payment on (expected_payment.payment_id = member_fee.payment_id)").
+		Columns("member_fee.period_id",
+			"period.start_date", "period.end_date",
+			"member_fee.amount_cents",
+			"coalesce(expected_payment.paid_cents, 0)",
+			"coalesce(expected_payment.reference_number, '')",
+			"member_fee.document_id")
+}
+
+func (m *Model) memberFeesFromSelect(q sq.SelectBuilder) []MemberFee {
+	noFees := []MemberFee{}
+	if !m.isAdmin() {
+		return noFees
+	}
+	rows, err := q.RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return noFees
+	}
+	defer rows.Close()
+	fees := noFees
+	for rows.Next() {
+		var fee MemberFee
+		var startDate, endDate, documentID sql.NullString
+		fee.Member, err = scanMember(rows, &fee.PeriodID,
+			&startDate, &endDate, &fee.AmountCents, &fee.PaidCents, &fee.ReferenceNumber,
+			&documentID)
+		if m.isErr(err) {
+			return noFees
+		}
+		fee.Period = fiFromISODate(startDate.String) + " - " +
+			fiFromISODate(endDate.String)
+		fee.DocumentID = documentID.String
+		if fee.DocumentID != "" {
+			fee.PaidCents = fee.AmountCents
+		}
+		fee.Amount = amountFromCents(fee.AmountCents)
+		fee.Paid = amountFromCents(fee.PaidCents)
+		fee.IsPaid = (fee.PaidCents >= fee.AmountCents)
+		fees = append(fees, fee)
+	}
+	if m.isErr(rows.Err()) {
+		return noFees
+	}
+	return fees
+}
+
+func (m *Model) GetMemberFees(periodID int64) []MemberFee {
+	return m.memberFeesFromSelect(selectMemberFee().
+		Where(sq.Eq{"member_fee.period_id": periodID}))
+}
+
+func (m *Model) GetMemberFeesOfMember(memberID string) []MemberFee {
+	return m.memberFeesFromSelect(selectMemberFee().
+		Where(sq.Eq{"member.member_id": memberID}).
+		OrderBy("member_fee.period_id desc"))
+}
+
+// GetUnpaidMemberFees lists the members who have not yet paid the
+// fee of the given period in full.
+func (m *Model) GetUnpaidMemberFees(periodID int64) []MemberFee {
+	unpaid := []MemberFee{}
+	for _, fee := range m.GetMemberFees(periodID) {
+		if !fee.IsPaid {
+			unpaid = append(unpaid, fee)
+		}
+	}
+	return unpaid
+}
+
+// ChargeMemberFees charges the fee of the period from every active
+// member of the given membership type who has not yet been charged.
+// Each fee is booked as a receivable and gets its own reference
+// number. Returns the number of members charged.
+func (m *Model) ChargeMemberFees(periodID int64, membershipType, amount,
+	receivableAccountID, revenueAccountID string) int {
+	if !m.isAdmin() {
+		return 0
+	}
+	period := m.GetPeriod(periodID)
+	if period == nil {
+		m.isErr(fmt.Errorf("No such period: %d", periodID))
+		return 0
+	}
+	amountCents, err := centsFromAmount(amount)
+	if m.isErr(err) {
+		return 0
+	}
+	charged := map[string]bool{}
+	for _, fee := range m.GetMemberFees(periodID) {
+		charged[fee.Member.MemberID] = true
+	}
+	// The receivables belong to the period even when they are charged
+	// before it starts or after it ends.
+	dateISO := time.Now().Format("2006-01-02")
+	if dateISO < period.StartDateISO {
+		dateISO = period.StartDateISO
+	} else if dateISO > period.EndDateISO {
+		dateISO = period.EndDateISO
+	}
+	count := 0
+	for _, mb := range m.GetMembers() {
+		if !mb.IsActive || charged[mb.MemberID] ||
+			mb.MembershipType != membershipType {
+			continue
+		}
+		description := fmt.Sprintf("Jäsenmaksu %s, %s", period, mb.FullName)
+		m.PostDocument(Document{
+			PaidDateFi:      fiFromISODate(dateISO),
+			Description:     description,
+			Amount:          amountFromCents(amountCents),
+			DebitAccountID:  receivableAccountID,
+			CreditAccountID: revenueAccountID,
+		})
+		if m.Err != nil {
+			return count
+		}
+		ref := m.PostExpectedPayment(ExpectedPayment{
+			Description:         description,
+			Amount:              amountFromCents(amountCents),
+			ReceivableAccountID: receivableAccountID,
+		})
+		if m.Err != nil {
+			return count
+		}
+		_, err := sq.Insert("member_fee").SetMap(sq.Eq{
+			"member_id":    mb.MemberID,
+			"period_id":    periodID,
+			"amount_cents": amountCents,
+			"payment_id": sq.Expr("(select payment_id from expected_payment where reference_number = ?)",
+				ref),
+		}).RunWith(m.tx).Exec()
+		if m.isErr(err) {
+			return count
+		}
+		count++
+	}
+	return count
+}
+
+// PutMemberFeeDocument marks a fee paid by the given document, for
+// fees that were paid without the reference number.
+func (m *Model) PutMemberFeeDocument(memberID string, periodID int64,
+	documentID string) {
+	if !m.isAdmin() {
+		return
+	}
+	var value interface{}
+	if documentID != "" {
+		if m.getIntFromDb(sq.Select("document_id").From("document").
+			Where(sq.Eq{"document_id": documentID})) == "" {
+			m.isErr(fmt.Errorf("No such document: %q", documentID))
+			return
+		}
+		value = documentID
+	}
+	result, err := sq.Update("member_fee").Set("document_id", value).
+		Where(sq.Eq{"member_id": memberID, "period_id": periodID}).
+		RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return
+	}
+	if count, err := result.RowsAffected(); m.isErr(err) {
+		return
+	} else if count != 1 {
+		m.isErr(fmt.Errorf("No fee of member %q in period %d",
+			memberID, periodID))
+	}
+}
@@ -0,0 +1,104 @@
+package model
+
+import "testing"
+
+func TestChargeMemberFees(t *testing.T) {
+	m := newTestModel(t)
+	m.PutSettings(Settings{BankAccountID: "1910"})
+	members := []Member{
+		{FullName: "Maksaja", MembershipType: "varsinainen"},
+		{FullName: "Velallinen", MembershipType: "varsinainen"},
+		{FullName: "Eronnut", MembershipType: "varsinainen",
+			LeaveDateFi: "1.1.2026"},
+		{FullName: "Kannattaja", MembershipType: "kannatus"},
+		{FullName: "Tositteella", MembershipType: "varsinainen"},
+	}
+	for _, mb := range members {
+		m.PostMember(mb)
+	}
+	tests := []struct {
+		membershipType string
+		want           int
+	}{
+		{"varsinainen", 3},
+		// Members who have been charged are not charged again.
+		{"varsinainen", 0},
+		{"kannatus", 1},
+	}
+	for _, test := range tests {
+		got := m.ChargeMemberFees(1, test.membershipType, "20,00",
+			"1700", "3000")
+		if m.Err != nil {
+			t.Fatal(m.Err)
+		}
+		if got != test.want {
+			t.Errorf("ChargeMemberFees(%q) = %d, want %d",
+				test.membershipType, got, test.want)
+		}
+	}
+	fees := map[string]MemberFee{}
+	for _, fee := range m.GetMemberFees(1) {
+		fees[fee.Member.FullName] = fee
+	}
+	m.PostBankPayments([]BankPayment{{DateFi: "5.3.2026", Cents: 2000,
+		ReferenceNumber: fees["Maksaja"].ReferenceNumber}})
+	m.PutMemberFeeDocument(fees["Tositteella"].Member.MemberID, 1,
+		postTestDocument(t, m, "6.3.2026", "20,00", "1910", "1700"))
+	if m.Err != nil {
+		t.Fatal(m.Err)
+	}
+	var unpaid []string
+	for _, fee := range m.GetUnpaidMemberFees(1) {
+		unpaid = append(unpaid, fee.Member.FullName)
+	}
+	want := []string{"Kannattaja", "Velallinen"}
+	if len(unpaid) != len(want) {
+		t.Fatalf("unpaid %v, want %v", unpaid, want)
+	}
+	for i := range want {
+		if unpaid[i] != want[i] {
+			t.Errorf("unpaid %v, want %v", unpaid, want)
+		}
+	}
+}
+
+func TestChargeMemberFeesOfPastPeriod(t *testing.T) {
+	m := newTestModel(t)
+	if _, err := m.tx.Exec(
+		`insert into period values (2, '2024-01-01', '2024-12-31')`); err != nil {
+		t.Fatal(err)
+	}
+	memberID := m.PostMember(Member{FullName: "Jäsen",
+		MembershipType: "varsinainen"})
+	if m.ChargeMemberFees(2, "varsinainen", "20,00", "1700", "3000") != 1 ||
+		m.Err != nil {
+		t.Fatalf("charging failed: %v", m.Err)
+	}
+	// The receivable is booked in the period that it belongs to.
+	var paidDate string
+	if err := m.tx.QueryRow(
+		`select paid_date from document`).Scan(&paidDate); err != nil {
+		t.Fatal(err)
+	}
+	if paidDate != "2024-12-31" {
+		t.Errorf("fee dated %s, want 2024-12-31", paidDate)
+	}
+	tests := []struct {
+		memberID   string
+		periodID   int64
+		documentID string
+	}{
+		{memberID, 2, "999"},
+		{memberID, 1, "1"},
+		{"999", 2, "1"},
+	}
+	for _, test := range tests {
+		m.PutMemberFeeDocument(test.memberID, test.periodID,
+			test.documentID)
+		if m.Err == nil {
+			t.Errorf("PutMemberFeeDocument(%q, %d, %q) succeeded",
+				test.memberID, test.periodID, test.documentID)
+		}
+		m.Err = nil
+	}
+}
@@ -0,0 +1,8 @@
+ALTER TABLE period_account ADD COLUMN 'is_cash' integer DEFAULT (0) NOT NULL;
+
+UPDATE period_account SET is_cash = 1
+WHERE nesting_level = 9
+AND (account_id BETWEEN 1900 AND 1999
+     OR account_id IN (SELECT value FROM setting WHERE name = "BankAccountID"));
+
+UPDATE version SET version = 11;
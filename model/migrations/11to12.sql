@@ -0,0 +1,14 @@
+-- Projects belong on revenue and expense entries only. Drop them from
+-- the other side of documents that have one.
+UPDATE document_entry SET project_id = NULL
+WHERE project_id IS NOT NULL
+AND account_id NOT IN
+    (SELECT account_id FROM period_account WHERE account_type IN (3, 4))
+AND EXISTS
+    (SELECT 1 FROM document_entry AS other
+     WHERE other.document_id = document_entry.document_id
+     AND other.project_id = document_entry.project_id
+     AND other.account_id IN
+         (SELECT account_id FROM period_account WHERE account_type IN (3, 4)));
+
+UPDATE version SET version = 12;
@@ -0,0 +1,3 @@
+ALTER TABLE 'user' ADD COLUMN 'pending' Boolean DEFAULT (0) NOT NULL;
+
+UPDATE version SET version = 2;
@@ -0,0 +1,14 @@
+CREATE TABLE 'audit_log' (
+  'audit_id' integer NOT NULL PRIMARY KEY,
+  'changed_time' varchar(255) NOT NULL,
+  'user_id' integer NULL REFERENCES 'user',
+  'object_type' varchar(255) NOT NULL,
+  'object_id' varchar(255) NOT NULL,
+  'field' varchar(255) NOT NULL,
+  'old_value' varchar(255) NULL,
+  'new_value' varchar(255) NULL
+);
+
+CREATE INDEX 'audit_log_object' ON 'audit_log' ('object_type', 'object_id');
+
+UPDATE version SET version = 3;
@@ -0,0 +1,13 @@
+ALTER TABLE 'document' ADD COLUMN 'reimbursement_state' varchar(255) NULL;
+ALTER TABLE 'document' ADD COLUMN 'reimbursed_date' varchar(255) NULL;
+ALTER TABLE 'user' ADD COLUMN 'iban' varchar(255) DEFAULT ('') NOT NULL;
+
+-- Expenses recorded before reimbursements were tracked have already
+-- been settled.
+UPDATE document SET reimbursement_state = 'paid'
+  WHERE paid_user_id IS NOT NULL;
+
+INSERT INTO setting values ("OrgIBAN", "");
+INSERT INTO setting values ("OrgBIC", "");
+
+UPDATE version SET version = 4;
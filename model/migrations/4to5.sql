@@ -0,0 +1,15 @@
+CREATE TABLE 'invoice' (
+  'invoice_id' integer NOT NULL PRIMARY KEY,
+  'customer_name' varchar(255) NOT NULL,
+  'customer_address' varchar(255) DEFAULT ('') NOT NULL,
+  'description' varchar(255) DEFAULT ('') NOT NULL,
+  'invoice_date' varchar(255) NOT NULL,
+  'due_date' varchar(255) NOT NULL,
+  'amount_cents' integer NOT NULL,
+  'reference_number' varchar(255) NOT NULL,
+  'receivable_account_id' integer NOT NULL,
+  'revenue_account_id' integer NOT NULL,
+  'document_id' integer NULL REFERENCES 'document'
+);
+
+UPDATE version SET version = 5;
@@ -0,0 +1,24 @@
+CREATE TABLE 'expected_payment' (
+  'payment_id' integer NOT NULL PRIMARY KEY,
+  'reference_number' varchar(255) NOT NULL UNIQUE,
+  'description' varchar(255) DEFAULT ('') NOT NULL,
+  'amount_cents' integer NOT NULL,
+  'due_date' varchar(255) NULL,
+  'receivable_account_id' integer NOT NULL,
+  'invoice_id' integer NULL REFERENCES 'invoice',
+  'paid_cents' integer DEFAULT (0) NOT NULL
+);
+
+CREATE TABLE 'bank_payment' (
+  'bank_payment_id' integer NOT NULL PRIMARY KEY,
+  'bank_key' varchar(255) NOT NULL UNIQUE,
+  'paid_date' varchar(255) NOT NULL,
+  'amount_cents' integer NOT NULL,
+  'reference_number' varchar(255) NOT NULL,
+  'payment_id' integer NULL REFERENCES 'expected_payment',
+  'document_id' integer NULL REFERENCES 'document'
+);
+
+INSERT INTO setting values ("BankAccountID", "");
+
+UPDATE version SET version = 6;
@@ -0,0 +1,21 @@
+CREATE TABLE 'member' (
+  'member_id' integer NOT NULL PRIMARY KEY,
+  'full_name' varchar(255) NOT NULL,
+  'email' varchar(255) DEFAULT ('') NOT NULL,
+  'phone' varchar(255) DEFAULT ('') NOT NULL,
+  'address' varchar(255) DEFAULT ('') NOT NULL,
+  'membership_type' varchar(255) DEFAULT ('') NOT NULL,
+  'join_date' varchar(255) NULL,
+  'leave_date' varchar(255) NULL
+);
+
+CREATE TABLE 'member_fee' (
+  'member_id' integer NOT NULL REFERENCES 'member',
+  'period_id' integer NOT NULL REFERENCES 'period',
+  'amount_cents' integer NOT NULL,
+  'payment_id' integer NULL REFERENCES 'expected_payment',
+  'document_id' integer NULL REFERENCES 'document',
+  PRIMARY KEY ('member_id', 'period_id')
+);
+
+UPDATE version SET version = 7;
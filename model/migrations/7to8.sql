@@ -0,0 +1,8 @@
+CREATE TABLE 'budget' (
+  'period_id' integer NOT NULL REFERENCES 'period',
+  'account_id' integer NOT NULL,
+  'amount_cents' integer NOT NULL,
+  PRIMARY KEY ('period_id', 'account_id')
+);
+
+UPDATE version SET version = 8;
@@ -0,0 +1,8 @@
+CREATE TABLE 'project' (
+  'project_id' integer NOT NULL PRIMARY KEY,
+  'title' varchar(255) NOT NULL
+);
+
+ALTER TABLE 'document_entry' ADD COLUMN 'project_id' integer NULL REFERENCES 'project';
+
+UPDATE version SET version = 9;
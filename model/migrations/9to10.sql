@@ -0,0 +1,10 @@
+INSERT INTO setting values ("StatementNotes", "Tilinpäätös on laadittu pien- ja mikroyrityksen tilinpäätöksessä esitettävistä tiedoista annetun asetuksen mukaisesti.");
+INSERT INTO setting values ("StatementSignatures", "Paikka ja aika
+
+
+Hallituksen puheenjohtaja
+
+
+Hallituksen jäsen");
+
+UPDATE version SET version = 10;
@@ -13,9 +13,10 @@ import (
 var db *sql.DB
 
 type Model struct {
-	user User
-	tx   *sql.Tx
-	Err  error
+	user    User
+	tx      *sql.Tx
+	Err     error
+	discard bool
 }
 
 func getVersion(tx *sql.Tx) int {
@@ -25,7 +26,10 @@ func getVersion(tx *sql.Tx) int {
 }
 
 func migrate(tx *sql.Tx) {
-	migs := []string{"/0to1.sql"}
+	migs := []string{"/0to1.sql", "/1to2.sql", "/2to3.sql",
+		"/3to4.sql", "/4to5.sql", "/5to6.sql",
+		"/6to7.sql", "/7to8.sql", "/8to9.sql",
+		"/9to10.sql", "/10to11.sql", "/11to12.sql"}
 	maxVersion := len(migs)
 	oldVersion := getVersion(tx)
 	log.Printf("Tietokannan versio: %d", oldVersion)
@@ -85,16 +89,33 @@ func MakeModel(userID int64, adminOnly bool) Model {
 	if m.isErr(err) {
 		return m
 	}
+	if m.user.IsPending {
+		m.Err = ErrPendingUser
+		return m
+	}
+	if m.user.PermissionLevel == NoPermission {
+		m.Err = errors.New("User deactivated")
+		return m
+	}
 	if adminOnly && !m.user.IsAdmin {
 		m.Forbidden()
 	}
 	return m
 }
 
+// Discard makes Close roll back the changes made so far instead of
+// committing them, e.g. when a report that changes data could not be
+// sent in full.
+func (m *Model) Discard() {
+	m.discard = true
+}
+
 func (m *Model) Close() {
 	if m.Err != nil {
 		log.Print(m.Err)
 		m.Err = m.tx.Rollback()
+	} else if m.discard {
+		m.Err = m.tx.Rollback()
 	} else {
 		m.Err = m.tx.Commit()
 	}
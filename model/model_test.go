@@ -0,0 +1,73 @@
+package model
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "massikone-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.SetOutput(io.Discard)
+	Initialize("sqlite://" + filepath.Join(dir, "massikone.db"))
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testBooks is a chart of accounts for 2026 with opening balances of
+// 1000,00 on the bank account and in equity.
+const testBooks = `
+insert into period values (1, '2026-01-01', '2026-12-31');
+insert into period_account
+ (period_id, account_id, account_type, title, nesting_level) values
+ (1, 1000, 0, 'VASTAAVAA', 0),
+ (1, 1700, 0, 'Myyntisaamiset', 9),
+ (1, 1900, 0, 'Käteinen', 9),
+ (1, 1910, 0, 'Pankkitili', 9),
+ (1, 2000, 2, 'VASTATTAVAA', 0),
+ (1, 2010, 2, 'Peruspääoma', 9),
+ (1, 2510, 1, 'Ostovelat', 9),
+ (1, 3000, 3, 'Jäsenmaksut', 9),
+ (1, 4000, 4, 'Kulut', 9);
+update period_account set starting_balance_cents = 100000
+ where account_id in (1910, 2010);
+update period_account set is_cash = 1 where account_id in (1900, 1910);
+`
+
+// newTestModel returns an administrator's model on the test books. Its
+// changes are rolled back when the test ends.
+func newTestModel(t *testing.T) *Model {
+	m := MakeModel(0, true)
+	if m.Err != nil {
+		t.Fatal(m.Err)
+	}
+	t.Cleanup(func() {
+		m.tx.Rollback()
+	})
+	if _, err := m.tx.Exec(testBooks); err != nil {
+		t.Fatal(err)
+	}
+	return &m
+}
+
+// postTestDocument posts a document and fails the test on error.
+func postTestDocument(t *testing.T, m *Model, dateFi, amount string,
+	debitAccountID, creditAccountID string) string {
+	documentID := m.PostDocument(Document{
+		PaidDateFi:      dateFi,
+		Description:     "Testi",
+		Amount:          amount,
+		DebitAccountID:  debitAccountID,
+		CreditAccountID: creditAccountID,
+	})
+	if m.Err != nil {
+		t.Fatal(m.Err)
+	}
+	return documentID
+}
@@ -0,0 +1,275 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	PaymentUnmatched = "unmatched"
+	PaymentDuplicate = "duplicate"
+	PaymentPaid      = "paid"
+	PaymentPartial   = "partial"
+	PaymentOverpaid  = "overpaid"
+)
+
+// ExpectedPayment is a receivable that the payer is to pay using a
+// reference number.
+type ExpectedPayment struct {
+	PaymentID           string
+	ReferenceNumber     string
+	Description         string
+	Amount              string
+	AmountCents         int64
+	DueDateFi           string
+	ReceivableAccountID string
+	InvoiceID           string
+	Paid                string
+	PaidCents           int64
+	Status              string
+	IsPaid              bool
+	IsPartial           bool
+	IsOverpaid          bool
+}
+
+// BankPayment is an incoming transaction from a bank statement.
+type BankPayment struct {
+	ArchivalID      string
+	DateFi          string
+	Cents           int64
+	ReferenceNumber string
+	Message         string
+}
+
+type BankPaymentResult struct {
+	BankPayment
+	Status     string
+	DocumentID string
+	Payment    *ExpectedPayment
+}
+
+func paymentStatus(amountCents, paidCents int64) string {
+	switch {
+	case paidCents == 0:
+		return ""
+	case paidCents < amountCents:
+		return PaymentPartial
+	case paidCents > amountCents:
+		return PaymentOverpaid
+	}
+	return PaymentPaid
+}
+
+func selectExpectedPayment() sq.SelectBuilder {
+	return sq.Select("payment_id, reference_number, description, amount_cents, due_date, receivable_account_id, invoice_id, paid_cents").
+		From("expected_payment").
+		OrderBy("payment_id")
+}
+
+func scanExpectedPayment(rows sq.RowScanner) (ExpectedPayment, error) {
+	var p ExpectedPayment
+	var dueDate sql.NullString
+	var invoiceID sql.NullString
+	err := rows.Scan(&p.PaymentID, &p.ReferenceNumber, &p.Description,
+		&p.AmountCents, &dueDate, &p.ReceivableAccountID, &invoiceID,
+		&p.PaidCents)
+	p.Amount = amountFromCents(p.AmountCents)
+	p.DueDateFi = fiFromISODate(dueDate.String)
+	p.InvoiceID = invoiceID.String
+	p.setPaidCents(p.PaidCents)
+	return p, err
+}
+
+func (p *ExpectedPayment) setPaidCents(paidCents int64) {
+	p.PaidCents = paidCents
+	p.Paid = amountFromCents(p.PaidCents)
+	p.Status = paymentStatus(p.AmountCents, p.PaidCents)
+	p.IsPaid = (p.Status == PaymentPaid)
+	p.IsPartial = (p.Status == PaymentPartial)
+	p.IsOverpaid = (p.Status == PaymentOverpaid)
+}
+
+func (m *Model) GetExpectedPayments() []ExpectedPayment {
+	noPayments := []ExpectedPayment{}
+	if !m.isAdmin() {
+		return noPayments
+	}
+	rows, err := selectExpectedPayment().RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return noPayments
+	}
+	defer rows.Close()
+	payments := noPayments
+	for rows.Next() {
+		p, err := scanExpectedPayment(rows)
+		if m.isErr(err) {
+			return noPayments
+		}
+		payments = append(payments, p)
+	}
+	if m.isErr(rows.Err()) {
+		return noPayments
+	}
+	return payments
+}
+
+func (m *Model) getExpectedPaymentByReference(ref string) *ExpectedPayment {
+	p, err := scanExpectedPayment(selectExpectedPayment().
+		Where(sq.Eq{"reference_number": ref}).RunWith(m.tx).QueryRow())
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if m.isErr(err) {
+		return nil
+	}
+	return &p
+}
+
+// PostExpectedPayment records a receivable to be paid with the given
+// reference number. A new reference number is made if none is given.
+func (m *Model) PostExpectedPayment(p ExpectedPayment) string {
+	if !m.isAdmin() {
+		return ""
+	}
+	amountCents, err := centsFromAmount(p.Amount)
+	if m.isErr(err) {
+		return ""
+	}
+	receivableAccountID := parsePositiveInt("account ID",
+		p.ReceivableAccountID)
+	if receivableAccountID < 1 {
+		m.isErr(fmt.Errorf("Receivable account missing"))
+		return ""
+	}
+	var paymentID int64
+	if m.isErr(sq.Select("coalesce(max(payment_id), 0) + 1").
+		From("expected_payment").RunWith(m.tx).Limit(1).QueryRow().
+		Scan(&paymentID)) {
+		return ""
+	}
+	ref := NormalizeReferenceNumber(p.ReferenceNumber)
+	if ref == "" {
+		ref = referenceFor(paymentReferencePrefix, paymentID)
+	}
+	if !IsValidReferenceNumber(ref) {
+		m.isErr(fmt.Errorf("Invalid reference number: %q", ref))
+		return ""
+	}
+	var dueDate interface{}
+	if iso := isoFromFiDate(p.DueDateFi); iso != "" {
+		dueDate = iso
+	}
+	var invoiceID interface{}
+	if p.InvoiceID != "" {
+		invoiceID = p.InvoiceID
+	}
+	_, err = sq.Insert("expected_payment").SetMap(sq.Eq{
+		"payment_id":            paymentID,
+		"reference_number":      ref,
+		"description":           p.Description,
+		"amount_cents":          amountCents,
+		"due_date":              dueDate,
+		"receivable_account_id": receivableAccountID,
+		"invoice_id":            invoiceID,
+	}).RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return ""
+	}
+	return ref
+}
+
+// bankPaymentKey tells bank transactions apart. Without an archival
+// ID, the transaction is known by its contents and by how many
+// transactions with the same contents come before it in the import, so
+// that two identical payments on the same day are both posted but
+// importing the same statement again posts neither.
+func bankPaymentKey(bp BankPayment, seen map[string]int) string {
+	if bp.ArchivalID != "" {
+		return bp.ArchivalID
+	}
+	key := fmt.Sprintf("%s:%d:%s:%s", isoFromFiDate(bp.DateFi), bp.Cents,
+		bp.ReferenceNumber, bp.Message)
+	seen[key]++
+	return fmt.Sprintf("%s:%d", key, seen[key])
+}
+
+// PostBankPayments matches incoming bank transactions to expected
+// payments by reference number. A matched transaction is posted as a
+// document that debits the bank account and credits the receivable.
+// The same transaction is never posted twice.
+func (m *Model) PostBankPayments(bankPayments []BankPayment) []BankPaymentResult {
+	results := []BankPaymentResult{}
+	if !m.isAdmin() {
+		return results
+	}
+	bankAccountID := m.GetSettings().BankAccountID
+	if parsePositiveInt("bank account ID", bankAccountID) < 1 {
+		m.isErr(fmt.Errorf("Bank account is not set"))
+		return results
+	}
+	seen := map[string]int{}
+	for _, bp := range bankPayments {
+		bp.ReferenceNumber = NormalizeReferenceNumber(bp.ReferenceNumber)
+		result := BankPaymentResult{BankPayment: bp}
+		result.Status = m.postBankPayment(bp, bankPaymentKey(bp, seen),
+			bankAccountID, &result)
+		if m.Err != nil {
+			return results
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (m *Model) postBankPayment(bp BankPayment, key, bankAccountID string,
+	result *BankPaymentResult) string {
+	if bp.Cents <= 0 || isoFromFiDate(bp.DateFi) == "" {
+		return PaymentUnmatched
+	}
+	p := m.getExpectedPaymentByReference(bp.ReferenceNumber)
+	if p == nil {
+		return PaymentUnmatched
+	}
+	result.Payment = p
+	var count int
+	if m.isErr(sq.Select("count(*)").From("bank_payment").
+		Where(sq.Eq{"bank_key": key}).RunWith(m.tx).QueryRow().
+		Scan(&count)) {
+		return ""
+	}
+	if count > 0 {
+		return PaymentDuplicate
+	}
+	documentID := m.PostDocument(Document{
+		PaidDateFi: bp.DateFi,
+		Description: fmt.Sprintf("Maksu viitteellä %s: %s",
+			p.ReferenceNumber, p.Description),
+		Amount:          amountFromCents(bp.Cents),
+		DebitAccountID:  bankAccountID,
+		CreditAccountID: p.ReceivableAccountID,
+	})
+	if m.Err != nil {
+		return ""
+	}
+	result.DocumentID = documentID
+	_, err := sq.Insert("bank_payment").SetMap(sq.Eq{
+		"bank_key":         key,
+		"paid_date":        isoFromFiDate(bp.DateFi),
+		"amount_cents":     bp.Cents,
+		"reference_number": bp.ReferenceNumber,
+		"payment_id":       p.PaymentID,
+		"document_id":      documentID,
+	}).RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return ""
+	}
+	p.setPaidCents(p.PaidCents + bp.Cents)
+	_, err = sq.Update("expected_payment").Set("paid_cents", p.PaidCents).
+		Where(sq.Eq{"payment_id": p.PaymentID}).RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return ""
+	}
+	return p.Status
+}
@@ -0,0 +1,113 @@
+package model
+
+import "testing"
+
+func TestPaymentStatus(t *testing.T) {
+	tests := []struct {
+		amountCents int64
+		paidCents   int64
+		want        string
+	}{
+		{5000, 0, ""},
+		{5000, 2000, PaymentPartial},
+		{5000, 5000, PaymentPaid},
+		{5000, 6000, PaymentOverpaid},
+	}
+	for _, test := range tests {
+		got := paymentStatus(test.amountCents, test.paidCents)
+		if got != test.want {
+			t.Errorf("paymentStatus(%d, %d) = %q, want %q",
+				test.amountCents, test.paidCents, got, test.want)
+		}
+	}
+}
+
+func TestBankPaymentKey(t *testing.T) {
+	payment := BankPayment{DateFi: "5.3.2026", Cents: 5000,
+		ReferenceNumber: "1232", Message: "Jäsenmaksu"}
+	archived := payment
+	archived.ArchivalID = "20260305ABC"
+	other := payment
+	other.Cents = 5001
+	tests := []struct {
+		payment BankPayment
+		want    string
+	}{
+		{payment, "2026-03-05:5000:1232:Jäsenmaksu:1"},
+		{archived, "20260305ABC"},
+		{other, "2026-03-05:5001:1232:Jäsenmaksu:1"},
+		{payment, "2026-03-05:5000:1232:Jäsenmaksu:2"},
+		{archived, "20260305ABC"},
+	}
+	seen := map[string]int{}
+	for i, test := range tests {
+		if got := bankPaymentKey(test.payment, seen); got != test.want {
+			t.Errorf("%d: bankPaymentKey() = %q, want %q",
+				i, got, test.want)
+		}
+	}
+}
+
+func TestPostBankPayments(t *testing.T) {
+	m := newTestModel(t)
+	m.PutSettings(Settings{BankAccountID: "1910"})
+	ref := m.PostExpectedPayment(ExpectedPayment{
+		Description:         "Jäsenmaksu",
+		Amount:              "50,00",
+		ReceivableAccountID: "1700",
+	})
+	if m.Err != nil {
+		t.Fatal(m.Err)
+	}
+	half := BankPayment{DateFi: "5.3.2026", Cents: 2500,
+		ReferenceNumber: ref}
+	tests := []struct {
+		payments []BankPayment
+		want     []string
+	}{
+		// Two identical payments in one import are both posted.
+		{[]BankPayment{half, half},
+			[]string{PaymentPartial, PaymentPaid}},
+		// Importing the same statement again posts nothing.
+		{[]BankPayment{half, half},
+			[]string{PaymentDuplicate, PaymentDuplicate}},
+		{[]BankPayment{{ArchivalID: "A1", DateFi: "6.3.2026",
+			Cents: 100, ReferenceNumber: " " + ref}},
+			[]string{PaymentOverpaid}},
+		{[]BankPayment{{ArchivalID: "A1", DateFi: "6.3.2026",
+			Cents: 100, ReferenceNumber: ref}},
+			[]string{PaymentDuplicate}},
+		{[]BankPayment{
+			{DateFi: "6.3.2026", Cents: 100, ReferenceNumber: "1232"},
+			{DateFi: "6.3.2026", Cents: 0, ReferenceNumber: ref},
+			{DateFi: "", Cents: 100, ReferenceNumber: ref}},
+			[]string{PaymentUnmatched, PaymentUnmatched,
+				PaymentUnmatched}},
+	}
+	for i, test := range tests {
+		results := m.PostBankPayments(test.payments)
+		if m.Err != nil {
+			t.Fatal(m.Err)
+		}
+		if len(results) != len(test.want) {
+			t.Fatalf("%d: got %d results, want %d",
+				i, len(results), len(test.want))
+		}
+		for j, result := range results {
+			if result.Status != test.want[j] {
+				t.Errorf("%d.%d: status %q, want %q",
+					i, j, result.Status, test.want[j])
+			}
+			posted := result.DocumentID != ""
+			if posted != (result.Status != PaymentDuplicate &&
+				result.Status != PaymentUnmatched) {
+				t.Errorf("%d.%d: status %q but document %q",
+					i, j, result.Status, result.DocumentID)
+			}
+		}
+	}
+	payments := m.GetExpectedPayments()
+	if len(payments) != 1 || payments[0].PaidCents != 5100 {
+		t.Errorf("expected payments %+v, want 5100 cents paid", payments)
+	}
+}
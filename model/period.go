@@ -0,0 +1,80 @@
+package model
+
+import (
+	"database/sql"
+	"strconv"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type Period struct {
+	PeriodID     int64
+	StartDateISO string
+	EndDateISO   string
+	StartDateFi  string
+	EndDateFi    string
+	IsMatch      bool
+}
+
+func scanPeriod(rows sq.RowScanner) (Period, error) {
+	var p Period
+	var startDate sql.NullString
+	var endDate sql.NullString
+	err := rows.Scan(&p.PeriodID, &startDate, &endDate)
+	p.StartDateISO = startDate.String
+	p.EndDateISO = endDate.String
+	p.StartDateFi = fiFromISODate(p.StartDateISO)
+	p.EndDateFi = fiFromISODate(p.EndDateISO)
+	return p, err
+}
+
+func (p Period) String() string {
+	return p.StartDateFi + " - " + p.EndDateFi
+}
+
+func (p Period) PeriodIDStr() string {
+	return strconv.FormatInt(p.PeriodID, 10)
+}
+
+// GetPeriods returns the accounting periods, latest first.
+func (m *Model) GetPeriods(matchPeriodID int64) []Period {
+	noPeriods := []Period{}
+	rows, err := sq.Select("period_id, start_date, end_date").
+		From("period").OrderBy("start_date desc, period_id desc").
+		RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return noPeriods
+	}
+	defer rows.Close()
+	periods := noPeriods
+	for rows.Next() {
+		p, err := scanPeriod(rows)
+		if m.isErr(err) {
+			return noPeriods
+		}
+		p.IsMatch = (p.PeriodID == matchPeriodID)
+		periods = append(periods, p)
+	}
+	if m.isErr(rows.Err()) {
+		return noPeriods
+	}
+	return periods
+}
+
+// GetPeriod returns the given period, or the latest one if periodID
+// is zero.
+func (m *Model) GetPeriod(periodID int64) *Period {
+	q := sq.Select("period_id, start_date, end_date").From("period").
+		OrderBy("start_date desc, period_id desc").Limit(1)
+	if periodID != 0 {
+		q = q.Where(sq.Eq{"period_id": periodID})
+	}
+	p, err := scanPeriod(q.RunWith(m.tx).QueryRow())
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if m.isErr(err) {
+		return nil
+	}
+	return &p
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Project is a cost center (kustannuspaikka) that document entries
+// can be assigned to, so that events and projects get their own
+// profit and loss.
+type Project struct {
+	ProjectID string
+	Title     string
+	IsMatch   bool
+}
+
+// ProjectSummary is the result of one project. Entries without a
+// project are summed under an empty ProjectID.
+type ProjectSummary struct {
+	Project      Project
+	Revenue      string
+	RevenueCents int64
+	Expense      string
+	ExpenseCents int64
+	Profit       string
+	ProfitCents  int64
+}
+
+func (m *Model) GetProjects(matchProjectID string) []Project {
+	noProjects := []Project{}
+	rows, err := sq.Select("project_id, title").From("project").
+		OrderBy("lower(title), project_id").RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return noProjects
+	}
+	defer rows.Close()
+	projects := noProjects
+	for rows.Next() {
+		var p Project
+		if m.isErr(rows.Scan(&p.ProjectID, &p.Title)) {
+			return noProjects
+		}
+		p.IsMatch = (matchProjectID != "" && p.ProjectID == matchProjectID)
+		projects = append(projects, p)
+	}
+	if m.isErr(rows.Err()) {
+		return noProjects
+	}
+	return projects
+}
+
+func (m *Model) GetProject(projectID string) *Project {
+	p := Project{ProjectID: projectID}
+	err := sq.Select("title").From("project").
+		Where(sq.Eq{"project_id": projectID}).
+		RunWith(m.tx).Limit(1).QueryRow().Scan(&p.Title)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if m.isErr(err) {
+		return nil
+	}
+	return &p
+}
+
+func (m *Model) PostProject(title string) string {
+	if !m.isAdmin() {
+		return ""
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		m.isErr(errors.New("Project title missing"))
+		return ""
+	}
+	var projectID int64
+	if m.isErr(sq.Select("coalesce(max(project_id), 0) + 1").
+		From("project").RunWith(m.tx).Limit(1).QueryRow().
+		Scan(&projectID)) {
+		return ""
+	}
+	_, err := sq.Insert("project").SetMap(sq.Eq{
+		"project_id": projectID,
+		"title":      title,
+	}).RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return ""
+	}
+	m.audit(AuditProject, strconv.FormatInt(projectID, 10),
+		"title", "", title)
+	return strconv.FormatInt(projectID, 10)
+}
+
+// GetProjectSummary sums the revenue, expenses and profit of each
+// project between the dates of the filter, which default to the latest
+// period.
+func (m *Model) GetProjectSummary(filter ReportFilter) []ProjectSummary {
+	noSummary := []ProjectSummary{}
+	if !m.isAdmin() {
+		return noSummary
+	}
+	acctMap := m.GetAccountMap()
+	byProject := map[string][]DocumentEntry{}
+	filter = m.StatementFilter(filter)
+	filter.ProjectID = ""
+	for _, entry := range m.getDocumentEntries(filter) {
+		byProject[entry.ProjectID] = append(byProject[entry.ProjectID], entry)
+	}
+	projects := append(m.GetProjects(""), Project{Title: "Ei projektia"})
+	summary := noSummary
+	for _, project := range projects {
+		entries := byProject[project.ProjectID]
+		balances, profit := GetAccountBalancesAndProfit(acctMap, entries)
+		s := ProjectSummary{Project: project, ProfitCents: profit}
+		for acctID, balance := range balances {
+			switch acctMap[acctID].AccountType {
+			case RevenueAccount:
+				s.RevenueCents += balance
+			case ExpenseAccount:
+				s.ExpenseCents += balance
+			}
+		}
+		if project.ProjectID == "" && len(entries) == 0 {
+			continue
+		}
+		s.Revenue = amountFromCents(s.RevenueCents)
+		s.Expense = amountFromCents(s.ExpenseCents)
+		s.Profit = amountFromCents(s.ProfitCents)
+		summary = append(summary, s)
+	}
+	return summary
+}
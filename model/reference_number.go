@@ -0,0 +1,54 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var referenceBase = regexp.MustCompile(`^\d{3,19}$`)
+
+// The reference numbers that we make start with a digit telling what
+// they are for, so that invoices and other expected payments never
+// get the same reference number.
+const (
+	invoiceReferencePrefix = 1
+	paymentReferencePrefix = 2
+)
+
+// referenceFor makes the reference number of an invoice or expected
+// payment from its ID.
+func referenceFor(prefix int, id int64) string {
+	return ReferenceNumber(fmt.Sprintf("%d%04d", prefix, id))
+}
+
+func referenceCheckDigit(base string) int {
+	weights := []int{7, 3, 1}
+	sum := 0
+	for i := 0; i < len(base); i++ {
+		digit := int(base[len(base)-1-i] - '0')
+		sum += digit * weights[i%len(weights)]
+	}
+	return (10 - sum%10) % 10
+}
+
+// ReferenceNumber appends the 7-3-1 check digit to base, giving a
+// Finnish creditor reference number (viitenumero).
+func ReferenceNumber(base string) string {
+	if !referenceBase.MatchString(base) {
+		return ""
+	}
+	return base + strconv.Itoa(referenceCheckDigit(base))
+}
+
+func NormalizeReferenceNumber(ref string) string {
+	return regexp.MustCompile(`[\s]+`).ReplaceAllString(ref, "")
+}
+
+func IsValidReferenceNumber(ref string) bool {
+	ref = NormalizeReferenceNumber(ref)
+	if len(ref) < 4 {
+		return false
+	}
+	return ReferenceNumber(ref[:len(ref)-1]) == ref
+}
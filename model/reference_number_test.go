@@ -0,0 +1,64 @@
+package model
+
+import "testing"
+
+func TestReferenceNumber(t *testing.T) {
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"123", "1232"},
+		{"1234", "12344"},
+		{"10005", "100052"},
+		{"1234567890123456789", "12345678901234567894"},
+		{"12", ""},
+		{"12345678901234567890", ""},
+		{"12a4", ""},
+	}
+	for _, test := range tests {
+		if got := ReferenceNumber(test.base); got != test.want {
+			t.Errorf("ReferenceNumber(%q) = %q, want %q",
+				test.base, got, test.want)
+		}
+	}
+}
+
+func TestReferenceFor(t *testing.T) {
+	tests := []struct {
+		prefix int
+		id     int64
+		want   string
+	}{
+		{invoiceReferencePrefix, 5, "100052"},
+		{paymentReferencePrefix, 5, "200059"},
+		{invoiceReferencePrefix, 12345, "1123452"},
+	}
+	for _, test := range tests {
+		if got := referenceFor(test.prefix, test.id); got != test.want {
+			t.Errorf("referenceFor(%d, %d) = %q, want %q",
+				test.prefix, test.id, got, test.want)
+		}
+	}
+}
+
+func TestIsValidReferenceNumber(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want bool
+	}{
+		{"1232", true},
+		{"12344", true},
+		{"1 2344", true},
+		{"10 0052", true},
+		{"1233", false},
+		{"123", false},
+		{"", false},
+		{"abcd", false},
+	}
+	for _, test := range tests {
+		if got := IsValidReferenceNumber(test.ref); got != test.want {
+			t.Errorf("IsValidReferenceNumber(%q) = %v, want %v",
+				test.ref, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package model
+
+import (
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	ReimbursementSubmitted = "submitted"
+	ReimbursementApproved  = "approved"
+	ReimbursementPaid      = "paid"
+)
+
+// Reimbursement tracks whether the association has paid back a
+// member who paid an expense out of their own pocket.
+type Reimbursement struct {
+	State          string
+	PaidDateISO    string
+	PaidDateFi     string
+	IsReimbursable bool
+	IsSubmitted    bool
+	IsApproved     bool
+	IsPaid         bool
+}
+
+type ReimbursementBalance struct {
+	User           User
+	SubmittedCents int64
+	ApprovedCents  int64
+	Submitted      string
+	Approved       string
+	Outstanding    string
+}
+
+func makeReimbursement(state, paidDateISO string) Reimbursement {
+	return Reimbursement{
+		State:          state,
+		PaidDateISO:    paidDateISO,
+		PaidDateFi:     fiFromISODate(paidDateISO),
+		IsReimbursable: (state != ""),
+		IsSubmitted:    (state == ReimbursementSubmitted),
+		IsApproved:     (state == ReimbursementApproved),
+		IsPaid:         (state == ReimbursementPaid),
+	}
+}
+
+func withReimbursement(document sq.SelectBuilder) sq.SelectBuilder {
+	return document.Columns("reimbursement_state", "reimbursed_date")
+}
+
+// PutReimbursement moves a reimbursable document to a new state. The
+// payment date is only kept for paid reimbursements. Closed documents
+// cannot be changed.
+func (m *Model) PutReimbursement(documentID, state, paidDateFi string) {
+	if !m.isAdmin() {
+		return
+	}
+	switch state {
+	case ReimbursementSubmitted, ReimbursementApproved:
+		paidDateFi = ""
+	case ReimbursementPaid:
+		if isoFromFiDate(paidDateFi) == "" {
+			m.isErr(fmt.Errorf("Invalid reimbursement date: %q",
+				paidDateFi))
+			return
+		}
+	default:
+		m.isErr(fmt.Errorf("Invalid reimbursement state: %q", state))
+		return
+	}
+	document := m.getOpenReimbursement(documentID)
+	if document == nil {
+		return
+	}
+	m.audit(AuditDocument, documentID, "reimbursement_state",
+		document.Reimbursement.State, state)
+	m.audit(AuditDocument, documentID, "reimbursed_date",
+		document.Reimbursement.PaidDateISO, isoFromFiDate(paidDateFi))
+	var paidDate interface{}
+	if paidDateFi != "" {
+		paidDate = isoFromFiDate(paidDateFi)
+	}
+	_, err := sq.Update("document").SetMap(sq.Eq{
+		"reimbursement_state": state,
+		"reimbursed_date":     paidDate,
+	}).Where(sq.Eq{"document_id": documentID}).RunWith(m.tx).Exec()
+	m.isErr(err)
+}
+
+// getOpenReimbursement returns the reimbursable document for changing
+// its reimbursement, or nil with m.Err set if there is no such
+// document or it is closed. The reimbursement of a closed document is
+// as final as its entries.
+func (m *Model) getOpenReimbursement(documentID string) *Document {
+	document := m.GetDocumentID(documentID)
+	if document == nil {
+		m.isErr(fmt.Errorf("No such document: %q", documentID))
+		return nil
+	}
+	if document.IsClosed {
+		m.isErr(ErrDocumentClosed)
+		return nil
+	}
+	if !document.Reimbursement.IsReimbursable {
+		m.isErr(fmt.Errorf("Document #%s is not reimbursable", documentID))
+		return nil
+	}
+	return document
+}
+
+func (m *Model) getReimbursementDocuments(states []string,
+	filter ReportFilter) []Document {
+	noDocuments := []Document{}
+	documents := noDocuments
+	q := filter.whereDocument(selectDocument().
+		Where(sq.Eq{"reimbursement_state": states}))
+	if !m.user.IsAdmin {
+		q = q.Where(sq.Eq{"paid_user_id": m.user.UserID})
+	}
+	rows, err := q.RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return noDocuments
+	}
+	defer rows.Close()
+	for rows.Next() {
+		document, err := scanDocument(rows)
+		if m.isErr(err) {
+			return noDocuments
+		}
+		documents = append(documents, document)
+	}
+	if m.isErr(rows.Err()) {
+		return noDocuments
+	}
+	return documents
+}
+
+// GetApprovedReimbursements returns the approved but not yet paid
+// reimbursements along with the bank account of each payee.
+func (m *Model) GetApprovedReimbursements() []Document {
+	if !m.isAdmin() {
+		return []Document{}
+	}
+	documents := m.getReimbursementDocuments(
+		[]string{ReimbursementApproved}, ReportFilter{})
+	for i := range documents {
+		user, err := m.getUserByID(documents[i].PaidUser.UserID)
+		if m.isErr(err) {
+			return []Document{}
+		}
+		documents[i].PaidUser = user
+	}
+	return documents
+}
+
+// GetReimbursementBalances returns how much each user is owed for the
+// expenses between the dates of the filter. A non-admin only sees
+// their own balance.
+func (m *Model) GetReimbursementBalances(filter ReportFilter) []ReimbursementBalance {
+	balances := []ReimbursementBalance{}
+	balanceIndex := map[int64]int{}
+	for _, document := range m.getReimbursementDocuments([]string{
+		ReimbursementSubmitted, ReimbursementApproved}, filter) {
+		i, ok := balanceIndex[document.PaidUser.UserID]
+		if !ok {
+			i = len(balances)
+			balanceIndex[document.PaidUser.UserID] = i
+			balances = append(balances, ReimbursementBalance{
+				User: document.PaidUser,
+			})
+		}
+		if document.Reimbursement.IsApproved {
+			balances[i].ApprovedCents += document.AmountCents
+		} else {
+			balances[i].SubmittedCents += document.AmountCents
+		}
+	}
+	for i := range balances {
+		b := &balances[i]
+		b.Submitted = amountFromCents(b.SubmittedCents)
+		b.Approved = amountFromCents(b.ApprovedCents)
+		b.Outstanding = amountFromCents(b.SubmittedCents + b.ApprovedCents)
+	}
+	return balances
+}
+
+// PayReimbursements marks the approved reimbursements of the documents
+// paid on the given date, once the payment batch made of them has been
+// sent to the bank. Like PutReimbursement, it refuses closed
+// documents.
+func (m *Model) PayReimbursements(documentIDs []string, paidDateFi string) {
+	if !m.isAdmin() {
+		return
+	}
+	paidDate := isoFromFiDate(paidDateFi)
+	if paidDate == "" {
+		m.isErr(fmt.Errorf("Invalid reimbursement date: %q", paidDateFi))
+		return
+	}
+	for _, documentID := range documentIDs {
+		if m.getOpenReimbursement(documentID) == nil {
+			return
+		}
+		result, err := sq.Update("document").SetMap(sq.Eq{
+			"reimbursement_state": ReimbursementPaid,
+			"reimbursed_date":     paidDate,
+		}).Where(sq.Eq{
+			"document_id":         documentID,
+			"reimbursement_state": ReimbursementApproved,
+		}).RunWith(m.tx).Exec()
+		if m.isErr(err) {
+			return
+		}
+		if n, err := result.RowsAffected(); m.isErr(err) {
+			return
+		} else if n != 1 {
+			m.isErr(fmt.Errorf(
+				"Reimbursement of document #%s is not approved",
+				documentID))
+			return
+		}
+		m.audit(AuditDocument, documentID, "reimbursement_state",
+			ReimbursementApproved, ReimbursementPaid)
+		m.audit(AuditDocument, documentID, "reimbursed_date",
+			"", paidDate)
+	}
+}
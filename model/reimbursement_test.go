@@ -0,0 +1,65 @@
+package model
+
+import "testing"
+
+func TestPayReimbursements(t *testing.T) {
+	m := newTestModel(t)
+	for i := 0; i < 3; i++ {
+		postTestDocument(t, m, "5.3.2026", "12,50", "4000", "2510")
+	}
+	if _, err := m.tx.Exec(`
+insert into user (user_id, full_name, iban)
+ values (1, 'Jäsen', 'FI2112345600000785');
+update document set paid_user_id = 1, reimbursement_state = 'approved';
+update document set reimbursement_state = 'submitted'
+ where document_id = 3;
+`); err != nil {
+		t.Fatal(err)
+	}
+	m.CloseDocument("2", DocumentApproved)
+	approvedIDs := func() []string {
+		var ids []string
+		for _, document := range m.GetApprovedReimbursements() {
+			ids = append(ids, document.DocumentID)
+		}
+		return ids
+	}
+	if ids := approvedIDs(); len(ids) != 2 {
+		t.Fatalf("approved %v, want 1 and 2", ids)
+	}
+	m.PayReimbursements([]string{"1"}, "10.3.2026")
+	if m.Err != nil {
+		t.Fatal(m.Err)
+	}
+	paid := m.GetDocumentID("1").Reimbursement
+	if !paid.IsPaid || paid.PaidDateFi != "10.3.2026" {
+		t.Errorf("reimbursement %+v, want paid on 10.3.2026", paid)
+	}
+	if ids := approvedIDs(); len(ids) != 1 || ids[0] != "2" {
+		t.Errorf("approved %v after paying, want 2", ids)
+	}
+	tests := []struct {
+		documentID string
+		paidDateFi string
+	}{
+		// Paying the same batch twice fails.
+		{"1", "10.3.2026"},
+		// Closed documents cannot be changed.
+		{"2", "10.3.2026"},
+		{"3", "10.3.2026"},
+		{"999", "10.3.2026"},
+		{"2", ""},
+	}
+	for _, test := range tests {
+		m.PayReimbursements([]string{test.documentID}, test.paidDateFi)
+		if m.Err == nil {
+			t.Errorf("PayReimbursements(%q, %q) succeeded",
+				test.documentID, test.paidDateFi)
+		}
+		m.Err = nil
+	}
+	m.PutReimbursement("2", ReimbursementPaid, "10.3.2026")
+	if m.Err == nil {
+		t.Errorf("PutReimbursement of a closed document succeeded")
+	}
+}
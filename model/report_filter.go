@@ -0,0 +1,196 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ReportFilter narrows the entries that a report covers. The zero
+// value covers everything. Dates are inclusive, as are the first and
+// last document numbers; zero leaves that end of the range open.
+type ReportFilter struct {
+	ProjectID       string
+	Accounts        []AccountRange
+	StartDateFi     string
+	EndDateFi       string
+	FirstDocumentID int
+	LastDocumentID  int
+}
+
+func parseFilterDate(what, fi string) (string, error) {
+	fi = strings.TrimSpace(fi)
+	if fi != "" && isoFromFiDate(fi) == "" {
+		return "", fmt.Errorf("Invalid %s: %q", what, fi)
+	}
+	return fi, nil
+}
+
+// ParseReportFilter builds a filter from the strings the user gave.
+// Accounts are given as for ParseAccountRanges and documents as a
+// single number or an inclusive range such as "10-20".
+func ParseReportFilter(projectID, accounts, startDateFi, endDateFi,
+	documents string) (ReportFilter, error) {
+	f := ReportFilter{ProjectID: projectID}
+	var err error
+	if f.Accounts, err = ParseAccountRanges(accounts); err != nil {
+		return f, err
+	}
+	if f.StartDateFi, err = parseFilterDate("start date", startDateFi); err != nil {
+		return f, err
+	}
+	if f.EndDateFi, err = parseFilterDate("end date", endDateFi); err != nil {
+		return f, err
+	}
+	if documents = strings.TrimSpace(documents); documents != "" {
+		bounds := strings.SplitN(documents, "-", 2)
+		if len(bounds) == 1 {
+			bounds = append(bounds, bounds[0])
+		}
+		for i, bound := range bounds {
+			bound = strings.TrimSpace(bound)
+			if bound == "" {
+				continue
+			}
+			n, err := strconv.Atoi(bound)
+			if err != nil || n < 1 {
+				return f, fmt.Errorf("Invalid document range: %q", documents)
+			}
+			if i == 0 {
+				f.FirstDocumentID = n
+			} else {
+				f.LastDocumentID = n
+			}
+		}
+	}
+	return f, nil
+}
+
+// DocumentRange formats the document number range the way
+// ParseReportFilter accepts it.
+func (f ReportFilter) DocumentRange() string {
+	if f.FirstDocumentID == 0 && f.LastDocumentID == 0 {
+		return ""
+	}
+	if f.FirstDocumentID == f.LastDocumentID {
+		return strconv.Itoa(f.FirstDocumentID)
+	}
+	s := ""
+	if f.FirstDocumentID != 0 {
+		s = strconv.Itoa(f.FirstDocumentID)
+	}
+	s += "-"
+	if f.LastDocumentID != 0 {
+		s += strconv.Itoa(f.LastDocumentID)
+	}
+	return s
+}
+
+// String describes the dates, accounts and documents covered, for
+// report headers.
+func (f ReportFilter) String() string {
+	var parts []string
+	switch {
+	case f.StartDateFi != "" && f.EndDateFi != "":
+		parts = append(parts, f.StartDateFi+" - "+f.EndDateFi)
+	case f.StartDateFi != "":
+		parts = append(parts, f.StartDateFi+" alkaen")
+	case f.EndDateFi != "":
+		parts = append(parts, f.EndDateFi+" asti")
+	}
+	if len(f.Accounts) > 0 {
+		parts = append(parts, "tilit "+FormatAccountRanges(f.Accounts))
+	}
+	if documents := f.DocumentRange(); documents != "" {
+		parts = append(parts, "tositteet "+documents)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f ReportFilter) whereDocument(q sq.SelectBuilder) sq.SelectBuilder {
+	if f.StartDateFi != "" {
+		q = q.Where(sq.GtOrEq{"paid_date": isoFromFiDate(f.StartDateFi)})
+	}
+	if f.EndDateFi != "" {
+		q = q.Where(sq.LtOrEq{"paid_date": isoFromFiDate(f.EndDateFi)})
+	}
+	if f.FirstDocumentID != 0 {
+		q = q.Where(sq.GtOrEq{"document.document_id": f.FirstDocumentID})
+	}
+	if f.LastDocumentID != 0 {
+		q = q.Where(sq.LtOrEq{"document.document_id": f.LastDocumentID})
+	}
+	return q
+}
+
+func (f ReportFilter) filtersEntries() bool {
+	return f.ProjectID != "" || len(f.Accounts) > 0
+}
+
+func (f ReportFilter) includesEntry(entry DocumentEntry) bool {
+	if f.ProjectID != "" && entry.ProjectID != f.ProjectID {
+		return false
+	}
+	if len(f.Accounts) > 0 && !AccountIDInRange(entry.AccountID, f.Accounts) {
+		return false
+	}
+	return true
+}
+
+func (f ReportFilter) filterEntries(entries []DocumentEntry) []DocumentEntry {
+	filtered := []DocumentEntry{}
+	for _, entry := range entries {
+		if f.includesEntry(entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// yearEarlier moves a date one year back. The last day of a month
+// stays the last day of the month, so 29.2. maps to 28.2.
+func yearEarlier(fi string) string {
+	date, err := time.Parse("2.1.2006", fi)
+	if err != nil {
+		return ""
+	}
+	if date.AddDate(0, 0, 1).Day() == 1 {
+		return date.AddDate(0, 0, 1).AddDate(-1, 0, -1).Format("2.1.2006")
+	}
+	return date.AddDate(-1, 0, 0).Format("2.1.2006")
+}
+
+// PreviousYear returns the filter with its dates moved one year back.
+func (f ReportFilter) PreviousYear() ReportFilter {
+	if f.StartDateFi != "" {
+		f.StartDateFi = yearEarlier(f.StartDateFi)
+	}
+	if f.EndDateFi != "" {
+		f.EndDateFi = yearEarlier(f.EndDateFi)
+	}
+	return f
+}
+
+// dayBefore returns the day before the start date of the filter.
+func (f ReportFilter) dayBefore() string {
+	date, err := time.Parse("2.1.2006", f.StartDateFi)
+	if err != nil {
+		return ""
+	}
+	return date.AddDate(0, 0, -1).Format("2.1.2006")
+}
+
+// getDocumentEntries returns the entries of the documents that the
+// filter covers. Accounts and projects are not filtered here.
+func (m *Model) getDocumentEntries(f ReportFilter) []DocumentEntry {
+	sql, args, err := f.whereDocument(
+		sq.Select("document_id").From("document")).ToSql()
+	if m.isErr(err) {
+		return []DocumentEntry{}
+	}
+	return m.documentEntriesFromSelect(selectDocumentEntry().
+		Where("document_id in ("+sql+")", args...))
+}
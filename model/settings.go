@@ -1,12 +1,22 @@
 package model
 
 import (
+	"fmt"
+	"strings"
+
 	sq "github.com/Masterminds/squirrel"
 )
 
 type Settings struct {
 	OrgFullName  string
 	OrgShortName string
+	OrgIBAN      string
+	OrgBIC       string
+	// Account debited when incoming bank payments are posted.
+	BankAccountID string
+	// Texts of the notes and signature pages of the full statement.
+	StatementNotes      string
+	StatementSignatures string
 }
 
 func getSetting(settings *Settings, name, value string) {
@@ -15,15 +25,45 @@ func getSetting(settings *Settings, name, value string) {
 		settings.OrgFullName = value
 	case "OrgShortName":
 		settings.OrgShortName = value
+	case "OrgIBAN":
+		settings.OrgIBAN = value
+	case "OrgBIC":
+		settings.OrgBIC = value
+	case "BankAccountID":
+		settings.BankAccountID = value
+	case "StatementNotes":
+		settings.StatementNotes = value
+	case "StatementSignatures":
+		settings.StatementSignatures = value
 	}
 }
 
 func (m *Model) PutSettings(settings Settings) {
-	m.putSetting("OrgFullName", settings.OrgFullName)
-	m.putSetting("OrgShortName", settings.OrgShortName)
+	iban := normalizeIBAN(settings.OrgIBAN)
+	if iban != "" && !IsValidIBAN(iban) {
+		m.isErr(fmt.Errorf("Invalid IBAN: %q", iban))
+		return
+	}
+	bic := strings.ToUpper(strings.TrimSpace(settings.OrgBIC))
+	if bic != "" && !isValidBIC(bic) {
+		m.isErr(fmt.Errorf("Invalid BIC: %q", bic))
+		return
+	}
+	old := m.GetSettings()
+	m.putSetting("OrgFullName", old.OrgFullName, settings.OrgFullName)
+	m.putSetting("OrgShortName", old.OrgShortName, settings.OrgShortName)
+	m.putSetting("OrgIBAN", old.OrgIBAN, iban)
+	m.putSetting("OrgBIC", old.OrgBIC, bic)
+	m.putSetting("BankAccountID", old.BankAccountID,
+		strings.TrimSpace(settings.BankAccountID))
+	m.putSetting("StatementNotes", old.StatementNotes,
+		strings.TrimSpace(settings.StatementNotes))
+	m.putSetting("StatementSignatures", old.StatementSignatures,
+		strings.TrimSpace(settings.StatementSignatures))
 }
 
-func (m *Model) putSetting(name, value string) {
+func (m *Model) putSetting(name, oldValue, value string) {
+	m.audit(AuditSetting, name, "value", oldValue, value)
 	_, err := sq.Update("setting").Set("value", value).
 		Where(sq.Eq{"name": name}).RunWith(m.tx).Exec()
 	m.isErr(err)
@@ -0,0 +1,467 @@
+package model
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// StatementRow is one line of the income statement or balance sheet,
+// with the balance of the period and of the same period a year
+// earlier. A heading row carries the totals of the accounts under it.
+type StatementRow struct {
+	Account
+	Balance              string
+	BalanceCents         int64
+	PreviousBalance      string
+	PreviousBalanceCents int64
+	IsTotal              bool
+}
+
+// Statement is an income statement or balance sheet of a period
+// compared to the same dates one year earlier.
+type Statement struct {
+	Period         string
+	PreviousPeriod string
+	Rows           []StatementRow
+}
+
+func isBalanceSheetAccount(acct Account) bool {
+	return !isIncomeStatementAccount(acct)
+}
+
+func totalRow(title string, cents, previousCents int64) StatementRow {
+	return StatementRow{
+		Account: Account{
+			Title:        title,
+			NestingLevel: accountNestingLevel,
+		},
+		Balance:              amountFromCents(cents),
+		BalanceCents:         cents,
+		PreviousBalance:      amountFromCents(previousCents),
+		PreviousBalanceCents: previousCents,
+		IsTotal:              true,
+	}
+}
+
+// statementRows lists the accounts accepted by include with their
+// balances in both periods, summing each heading over the accounts
+// under it. Accounts without a balance in either period and headings
+// with nothing under them are left out. Accounts that have a balance
+// but are no longer in the chart come last, as they are in the chart
+// of the previous period or else just under their number.
+func (m *Model) statementRows(include func(Account) bool,
+	balances, previous map[int]int64,
+	previousAcctMap map[int]Account) []StatementRow {
+	var rows []StatementRow
+	var headings []int
+	usedHeadings := map[int]bool{}
+	listed := map[int]bool{}
+	addAccount := func(acct Account) {
+		cents, used := balances[acct.AccountID]
+		previousCents, previousUsed := previous[acct.AccountID]
+		if !used && !previousUsed {
+			return
+		}
+		for _, i := range headings {
+			rows[i].BalanceCents += cents
+			rows[i].PreviousBalanceCents += previousCents
+			usedHeadings[i] = true
+		}
+		rows = append(rows, StatementRow{
+			Account:              acct,
+			BalanceCents:         cents,
+			PreviousBalanceCents: previousCents,
+		})
+	}
+	for _, acct := range m.GetAccountList(false, "") {
+		if !acct.IsHeading() {
+			listed[acct.AccountID] = true
+		}
+		if !include(acct) {
+			continue
+		}
+		if acct.IsHeading() {
+			for len(headings) > 0 &&
+				rows[headings[len(headings)-1]].NestingLevel >= acct.NestingLevel {
+				headings = headings[:len(headings)-1]
+			}
+			headings = append(headings, len(rows))
+			rows = append(rows, StatementRow{Account: acct})
+			continue
+		}
+		addAccount(acct)
+	}
+	headings = nil
+	var unlisted []int
+	for _, acctMap := range []map[int]int64{balances, previous} {
+		for acctID := range acctMap {
+			if !listed[acctID] {
+				listed[acctID] = true
+				unlisted = append(unlisted, acctID)
+			}
+		}
+	}
+	sort.Ints(unlisted)
+	for _, acctID := range unlisted {
+		acct, ok := previousAcctMap[acctID]
+		if !ok {
+			acct = Account{
+				AccountID:    acctID,
+				AccountIDStr: strconv.Itoa(acctID),
+				NestingLevel: accountNestingLevel,
+			}
+		}
+		if include(acct) {
+			addAccount(acct)
+		}
+	}
+	used := []StatementRow{}
+	for i, row := range rows {
+		if row.IsHeading() && !usedHeadings[i] {
+			continue
+		}
+		row.Balance = amountFromCents(row.BalanceCents)
+		row.PreviousBalance = amountFromCents(row.PreviousBalanceCents)
+		used = append(used, row)
+	}
+	return used
+}
+
+// StatementFilter keeps the project and dates of the filter, filling
+// in the dates it leaves open from the latest period.
+func (m *Model) StatementFilter(filter ReportFilter) ReportFilter {
+	filter = ReportFilter{
+		ProjectID:   filter.ProjectID,
+		StartDateFi: filter.StartDateFi,
+		EndDateFi:   filter.EndDateFi,
+	}
+	if filter.StartDateFi != "" && filter.EndDateFi != "" {
+		return filter
+	}
+	if period := m.GetPeriod(0); period != nil {
+		if filter.StartDateFi == "" {
+			filter.StartDateFi = period.StartDateFi
+		}
+		if filter.EndDateFi == "" {
+			filter.EndDateFi = period.EndDateFi
+		}
+	}
+	return filter
+}
+
+// ReportPeriod describes the dates that a report of the filter covers
+// when they default to the latest period.
+func (m *Model) ReportPeriod(filter ReportFilter) string {
+	filter = m.StatementFilter(filter)
+	filter.ProjectID = ""
+	return filter.String()
+}
+
+// ReportYear is the year of the end date of a report of the filter,
+// which defaults to the latest period, or this year without periods.
+func (m *Model) ReportYear(filter ReportFilter) string {
+	endDate := isoFromFiDate(m.StatementFilter(filter).EndDateFi)
+	if endDate == "" {
+		return strconv.Itoa(time.Now().Year())
+	}
+	return endDate[:4]
+}
+
+// previousYear returns the filter moved one year back and the period
+// that it ends in, or nil if there is no accounting period to compare
+// to.
+func (m *Model) previousYear(filter ReportFilter) (ReportFilter, *Period) {
+	previous := filter.PreviousYear()
+	period := m.getPeriodAt(isoFromFiDate(previous.EndDateFi))
+	if period == nil {
+		return ReportFilter{}, nil
+	}
+	return previous, period
+}
+
+// previousAccountMap returns the chart of the period to compare to, so
+// that the previous year is classified as it was then. Accounts that
+// are only in the current chart keep their current type.
+func (m *Model) previousAccountMap(acctMap map[int]Account,
+	period *Period) map[int]Account {
+	previous := m.accountMapFromSelect(selectPeriodAccount(period.PeriodID))
+	for acctID, acct := range acctMap {
+		if _, ok := previous[acctID]; !ok {
+			previous[acctID] = acct
+		}
+	}
+	return previous
+}
+
+// insertStatementRow puts the row after rows[after] and adds its
+// amounts to the headings that rows[after] is under.
+func insertStatementRow(rows []StatementRow, after int,
+	row StatementRow) []StatementRow {
+	level := accountNestingLevel
+	if after >= 0 && rows[after].IsHeading() {
+		level = rows[after].NestingLevel + 1
+	}
+	for i := after; i >= 0 && level > 0; i-- {
+		heading := &rows[i]
+		if !heading.IsHeading() || heading.NestingLevel >= level {
+			continue
+		}
+		level = heading.NestingLevel
+		heading.BalanceCents += row.BalanceCents
+		heading.PreviousBalanceCents += row.PreviousBalanceCents
+		heading.Balance = amountFromCents(heading.BalanceCents)
+		heading.PreviousBalance = amountFromCents(
+			heading.PreviousBalanceCents)
+	}
+	rows = append(rows, StatementRow{})
+	copy(rows[after+2:], rows[after+1:])
+	rows[after+1] = row
+	return rows
+}
+
+func (m *Model) incomeStatementBalances(acctMap map[int]Account,
+	filter ReportFilter) (map[int]int64, int64) {
+	entries := filter.filterEntries(m.getDocumentEntries(filter))
+	balances, profit := GetAccountBalancesAndProfit(acctMap, entries)
+	for acctID, cents := range balances {
+		if acctMap[acctID].AccountType == ExpenseAccount {
+			balances[acctID] = -cents
+		}
+	}
+	return balances, profit
+}
+
+// GetIncomeStatement returns the revenue and expense accounts with
+// their balances, expenses as negative amounts, followed by the
+// profit. The dates of the filter default to the latest period, and
+// if the filter has a project, only the entries of that project are
+// counted.
+func (m *Model) GetIncomeStatement(filter ReportFilter) Statement {
+	filter = m.StatementFilter(filter)
+	previousFilter, previousPeriod := m.previousYear(filter)
+	statement := Statement{
+		Period:         filter.String(),
+		PreviousPeriod: previousFilter.String(),
+		Rows:           []StatementRow{},
+	}
+	if !m.isAdmin() {
+		return statement
+	}
+	acctMap := m.GetAccountMap()
+	balances, profit := m.incomeStatementBalances(acctMap, filter)
+	previous := map[int]int64{}
+	previousAcctMap := acctMap
+	var previousProfit int64
+	if previousPeriod != nil {
+		previousAcctMap = m.previousAccountMap(acctMap, previousPeriod)
+		previous, previousProfit = m.incomeStatementBalances(
+			previousAcctMap, previousFilter)
+	}
+	statement.Rows = append(
+		m.statementRows(isIncomeStatementAccount, balances, previous,
+			previousAcctMap),
+		totalRow("Tilikauden tulos", profit, previousProfit))
+	return statement
+}
+
+// getPeriodAt returns the period that the date falls in, or nil if
+// there is none.
+func (m *Model) getPeriodAt(dateISO string) *Period {
+	if dateISO == "" {
+		return nil
+	}
+	p, err := scanPeriod(sq.Select("period_id, start_date, end_date").
+		From("period").
+		Where(sq.LtOrEq{"start_date": dateISO}).
+		Where(sq.GtOrEq{"end_date": dateISO}).
+		OrderBy("start_date desc, period_id desc").Limit(1).
+		RunWith(m.tx).QueryRow())
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if m.isErr(err) {
+		return nil
+	}
+	return &p
+}
+
+// getStartingBalances returns the balances that the accounts of the
+// period start from.
+func (m *Model) getStartingBalances(periodID int64) map[int]int64 {
+	balances := map[int]int64{}
+	rows, err := sq.Select("account_id, starting_balance_cents").
+		From("period_account").
+		Where(sq.Eq{
+			"period_id":     periodID,
+			"nesting_level": accountNestingLevel,
+		}).
+		Where("starting_balance_cents <> 0").
+		RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return balances
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var acctID int
+		var cents int64
+		if m.isErr(rows.Scan(&acctID, &cents)) {
+			return balances
+		}
+		balances[acctID] += cents
+	}
+	m.isErr(rows.Err())
+	return balances
+}
+
+// getOpeningBalances returns the balance of each account at the start
+// of the date, and the profit made before the date that is not in the
+// starting balances. The starting balances of the period that the date
+// falls in already hold everything before the period, so only the
+// entries from the start of the period are added to them. Without a
+// period, all entries before the date are counted.
+func (m *Model) getOpeningBalances(acctMap map[int]Account,
+	startDateFi string) (map[int]int64, int64) {
+	if startDateFi == "" {
+		return map[int]int64{}, 0
+	}
+	before := ReportFilter{EndDateFi: ReportFilter{
+		StartDateFi: startDateFi}.dayBefore()}
+	balances := map[int]int64{}
+	if period := m.getPeriodAt(isoFromFiDate(startDateFi)); period != nil {
+		balances = m.getStartingBalances(period.PeriodID)
+		if period.StartDateFi == startDateFi {
+			return balances, 0
+		}
+		before.StartDateFi = period.StartDateFi
+	}
+	changes, profit := GetAccountBalancesAndProfit(acctMap,
+		m.getDocumentEntries(before))
+	for acctID, cents := range changes {
+		balances[acctID] += cents
+	}
+	return balances, profit
+}
+
+// firstAccountOfType returns the lowest numbered account of the given
+// type, or zero if the chart has none.
+func firstAccountOfType(acctMap map[int]Account, acctType int) int {
+	found := 0
+	for acctID, acct := range acctMap {
+		if acct.AccountType == acctType && (found == 0 || acctID < found) {
+			found = acctID
+		}
+	}
+	return found
+}
+
+// balanceSheetBalances returns the account balances at the end date
+// of the filter, the profit between its start and end dates, and the
+// profit before the start date that the starting balances do not hold
+// yet.
+func (m *Model) balanceSheetBalances(acctMap map[int]Account,
+	filter ReportFilter) (map[int]int64, int64, int64) {
+	balances, pastProfit := m.getOpeningBalances(acctMap, filter.StartDateFi)
+	current, profit := GetAccountBalancesAndProfit(acctMap,
+		m.getDocumentEntries(filter))
+	for acctID, cents := range current {
+		balances[acctID] += cents
+	}
+	return balances, profit, pastProfit
+}
+
+func balanceSheetTotals(acctMap map[int]Account,
+	balances map[int]int64) (assetCents, liabilityCents int64) {
+	for acctID, cents := range balances {
+		switch acct := acctMap[acctID]; {
+		case acct.AccountType == AssetAccount:
+			assetCents += cents
+		case isBalanceSheetAccount(acct):
+			liabilityCents += cents
+		}
+	}
+	return assetCents, liabilityCents
+}
+
+// GetBalanceSheet returns the assets followed by the liabilities and
+// equity at the end date of the filter, each side with its total. The
+// dates of the filter default to the latest period. The profit of the
+// period is shown on the profit account of the chart, or on its own
+// row after the equity accounts if the chart has none. Profit from
+// before the start date that the starting balances do not hold goes
+// to the past profit account in the same way.
+func (m *Model) GetBalanceSheet(filter ReportFilter) Statement {
+	filter = m.StatementFilter(filter)
+	previousFilter, previousPeriod := m.previousYear(filter)
+	statement := Statement{
+		Period:         filter.String(),
+		PreviousPeriod: previousFilter.String(),
+		Rows:           []StatementRow{},
+	}
+	if !m.isAdmin() {
+		return statement
+	}
+	acctMap := m.GetAccountMap()
+	balances, profit, pastProfit := m.balanceSheetBalances(acctMap, filter)
+	previous := map[int]int64{}
+	previousAcctMap := acctMap
+	var previousProfit, previousPastProfit int64
+	if previousPeriod != nil {
+		previousAcctMap = m.previousAccountMap(acctMap, previousPeriod)
+		previous, previousProfit, previousPastProfit =
+			m.balanceSheetBalances(previousAcctMap, previousFilter)
+	}
+	var extraRows []StatementRow
+	for _, result := range []struct {
+		acctType             int
+		title                string
+		cents, previousCents int64
+	}{
+		{PastProfitAccount, "Edellisten tilikausien tulos",
+			pastProfit, previousPastProfit},
+		{ProfitAccount, "Tilikauden tulos", profit, previousProfit},
+	} {
+		if acctID := firstAccountOfType(acctMap, result.acctType); acctID != 0 {
+			balances[acctID] += result.cents
+			previous[acctID] += result.previousCents
+		} else if result.cents != 0 || result.previousCents != 0 ||
+			result.acctType == ProfitAccount {
+			extraRows = append(extraRows, totalRow(result.title,
+				result.cents, result.previousCents))
+		}
+	}
+	assetCents, liabilityCents := balanceSheetTotals(acctMap, balances)
+	previousAssetCents, previousLiabilityCents := balanceSheetTotals(
+		previousAcctMap, previous)
+	rows := m.statementRows(func(acct Account) bool {
+		return acct.AccountType == AssetAccount
+	}, balances, previous, previousAcctMap)
+	rows = append(rows, totalRow("Vastaavaa yhteensä",
+		assetCents, previousAssetCents))
+	liabilityRows := m.statementRows(func(acct Account) bool {
+		return acct.AccountType != AssetAccount && isBalanceSheetAccount(acct)
+	}, balances, previous, previousAcctMap)
+	// The results without an account of their own go after the last
+	// equity account, or last if there is none, and count towards
+	// the headings above it.
+	after := len(liabilityRows) - 1
+	for i, row := range liabilityRows {
+		if !row.IsHeading() && !row.IsTotal &&
+			acctMap[row.AccountID].AccountType == EquityAccount {
+			after = i
+		}
+	}
+	for _, row := range extraRows {
+		liabilityCents += row.BalanceCents
+		previousLiabilityCents += row.PreviousBalanceCents
+		liabilityRows = insertStatementRow(liabilityRows, after, row)
+		after++
+	}
+	rows = append(rows, liabilityRows...)
+	statement.Rows = append(rows, totalRow("Vastattavaa yhteensä",
+		liabilityCents, previousLiabilityCents))
+	return statement
+}
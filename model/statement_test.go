@@ -0,0 +1,72 @@
+package model
+
+import "testing"
+
+func TestStatementsPreviousChart(t *testing.T) {
+	m := newTestModel(t)
+	// The supplies and loan accounts of 2026 are left out of the
+	// chart of 2027.
+	if _, err := m.tx.Exec(`
+insert into period_account
+ (period_id, account_id, account_type, title, nesting_level) values
+ (1, 2600, 1, 'Lainat', 9),
+ (1, 4100, 4, 'Tarvikkeet', 9);
+insert into period values (2, '2027-01-01', '2027-12-31');
+insert into period_account
+ (period_id, account_id, account_type, title, nesting_level,
+  starting_balance_cents, is_cash)
+ select 2, account_id, account_type, title, nesting_level,
+  starting_balance_cents, is_cash
+ from period_account where period_id = 1 and account_id < 2600;
+insert into period_account
+ (period_id, account_id, account_type, title, nesting_level) values
+ (2, 3000, 3, 'Jäsenmaksut', 9),
+ (2, 4000, 4, 'Kulut', 9);
+`); err != nil {
+		t.Fatal(err)
+	}
+	postTestDocument(t, m, "10.3.2026", "24,46", "4100", "1910")
+	postTestDocument(t, m, "12.3.2026", "100,00", "1910", "2600")
+	postTestDocument(t, m, "5.2.2027", "12,50", "4000", "1910")
+	previousBalances := func(statement Statement) map[string]string {
+		balances := map[string]string{}
+		for _, row := range statement.Rows {
+			key := row.Title
+			if !row.IsTotal {
+				key = row.AccountIDStr
+			}
+			balances[key] = row.PreviousBalance
+		}
+		return balances
+	}
+	tests := []struct {
+		name      string
+		statement Statement
+		want      map[string]string
+	}{
+		{"income statement", m.GetIncomeStatement(ReportFilter{}),
+			map[string]string{
+				"4100":             "-24,46",
+				"Tilikauden tulos": "-24,46",
+			}},
+		{"balance sheet", m.GetBalanceSheet(ReportFilter{}),
+			map[string]string{
+				"2600":                 "100,00",
+				"Vastaavaa yhteensä":   "1075,54",
+				"Tilikauden tulos":     "-24,46",
+				"Vastattavaa yhteensä": "1075,54",
+			}},
+	}
+	if m.Err != nil {
+		t.Fatal(m.Err)
+	}
+	for _, test := range tests {
+		got := previousBalances(test.statement)
+		for key, want := range test.want {
+			if got[key] != want {
+				t.Errorf("%s: previous %s %q, want %q",
+					test.name, key, got[key], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"sort"
+)
+
+type TrialBalanceRow struct {
+	Account
+	OpeningBalance      string
+	OpeningBalanceCents int64
+	Debit               string
+	DebitCents          int64
+	Credit              string
+	CreditCents         int64
+	ClosingBalance      string
+	ClosingBalanceCents int64
+}
+
+// TrialBalance (saldoluettelo) sums the debits and credits of each
+// account. In a consistent set of books total debits equal total
+// credits.
+type TrialBalance struct {
+	Period           string
+	Rows             []TrialBalanceRow
+	TotalDebit       string
+	TotalDebitCents  int64
+	TotalCredit      string
+	TotalCreditCents int64
+	Difference       string
+	IsBalanced       bool
+}
+
+// GetTrialBalance lists every account that has an opening balance or
+// entries between the dates of the filter, which default to the
+// latest period. Balances grow in the normal direction of each
+// account.
+func (m *Model) GetTrialBalance(filter ReportFilter) TrialBalance {
+	filter = m.StatementFilter(filter)
+	filter.ProjectID = ""
+	tb := TrialBalance{Period: filter.String()}
+	if !m.isAdmin() {
+		return tb
+	}
+	acctMap := m.GetAccountMap()
+	rowMap := map[int]*TrialBalanceRow{}
+	getRow := func(acctID int) *TrialBalanceRow {
+		row, ok := rowMap[acctID]
+		if !ok {
+			acct, ok := acctMap[acctID]
+			if !ok {
+				acct = Account{AccountID: acctID}
+			}
+			row = &TrialBalanceRow{Account: acct}
+			rowMap[acctID] = row
+		}
+		return row
+	}
+	opening, _ := m.getOpeningBalances(acctMap, filter.StartDateFi)
+	for acctID, cents := range opening {
+		row := getRow(acctID)
+		row.OpeningBalanceCents = cents
+		row.ClosingBalanceCents = cents
+	}
+	for _, entry := range m.getDocumentEntries(filter) {
+		row := getRow(entry.AccountID)
+		cents := entry.UnitCount * entry.UnitCostCents
+		if entry.IsDebit {
+			row.DebitCents += cents
+			tb.TotalDebitCents += cents
+		} else {
+			row.CreditCents += cents
+			tb.TotalCreditCents += cents
+		}
+		if entrySubtractsFromBalance(entry.IsDebit, row.AccountType) {
+			row.ClosingBalanceCents -= cents
+		} else {
+			row.ClosingBalanceCents += cents
+		}
+	}
+	for _, row := range rowMap {
+		row.OpeningBalance = amountFromCents(row.OpeningBalanceCents)
+		row.Debit = amountFromCents(row.DebitCents)
+		row.Credit = amountFromCents(row.CreditCents)
+		row.ClosingBalance = amountFromCents(row.ClosingBalanceCents)
+		tb.Rows = append(tb.Rows, *row)
+	}
+	sort.Slice(tb.Rows, func(i, j int) bool {
+		return tb.Rows[i].AccountID < tb.Rows[j].AccountID
+	})
+	tb.TotalDebit = amountFromCents(tb.TotalDebitCents)
+	tb.TotalCredit = amountFromCents(tb.TotalCreditCents)
+	tb.Difference = amountFromCents(tb.TotalDebitCents - tb.TotalCreditCents)
+	tb.IsBalanced = (tb.TotalDebitCents == tb.TotalCreditCents)
+	return tb
+}
@@ -0,0 +1,86 @@
+package model
+
+import "testing"
+
+func TestGetTrialBalance(t *testing.T) {
+	m := newTestModel(t)
+	postTestDocument(t, m, "5.3.2026", "12,50", "4000", "1910")
+	postTestDocument(t, m, "7.3.2026", "50,00", "1910", "3000")
+	postTestDocument(t, m, "1.4.2026", "100,00", "1900", "1910")
+	postTestDocument(t, m, "9.4.2026", "24,46", "4000", "1900")
+	// Opening balance, debit, credit and closing balance.
+	type row [4]string
+	tests := []struct {
+		filter ReportFilter
+		rows   map[int]row
+		total  string
+	}{
+		{ReportFilter{}, map[int]row{
+			1900: {"", "100,00", "24,46", "75,54"},
+			1910: {"1000,00", "50,00", "112,50", "937,50"},
+			2010: {"1000,00", "", "", "1000,00"},
+			3000: {"", "", "50,00", "50,00"},
+			4000: {"", "36,96", "", "36,96"},
+		}, "186,96"},
+		{ReportFilter{StartDateFi: "1.4.2026", EndDateFi: "30.4.2026"},
+			map[int]row{
+				1900: {"", "100,00", "24,46", "75,54"},
+				1910: {"1037,50", "", "100,00", "937,50"},
+				2010: {"1000,00", "", "", "1000,00"},
+				3000: {"50,00", "", "", "50,00"},
+				4000: {"12,50", "24,46", "", "36,96"},
+			}, "124,46"},
+		{ReportFilter{StartDateFi: "1.5.2026", EndDateFi: "31.5.2026"},
+			map[int]row{
+				1900: {"75,54", "", "", "75,54"},
+				1910: {"937,50", "", "", "937,50"},
+				2010: {"1000,00", "", "", "1000,00"},
+				3000: {"50,00", "", "", "50,00"},
+				4000: {"36,96", "", "", "36,96"},
+			}, ""},
+	}
+	for i, test := range tests {
+		tb := m.GetTrialBalance(test.filter)
+		if m.Err != nil {
+			t.Fatal(m.Err)
+		}
+		got := map[int]row{}
+		for _, r := range tb.Rows {
+			if r.OpeningBalanceCents != 0 || r.DebitCents != 0 ||
+				r.CreditCents != 0 {
+				got[r.AccountID] = row{r.OpeningBalance, r.Debit,
+					r.Credit, r.ClosingBalance}
+			}
+		}
+		if len(got) != len(test.rows) {
+			t.Errorf("%d: rows %v, want %v", i, got, test.rows)
+		}
+		for acctID, want := range test.rows {
+			if got[acctID] != want {
+				t.Errorf("%d: account %d %v, want %v",
+					i, acctID, got[acctID], want)
+			}
+		}
+		if tb.TotalDebit != test.total || tb.TotalCredit != test.total ||
+			!tb.IsBalanced {
+			t.Errorf("%d: totals %q and %q, want %q",
+				i, tb.TotalDebit, tb.TotalCredit, test.total)
+		}
+	}
+}
+
+func TestGetTrialBalanceUnbalanced(t *testing.T) {
+	m := newTestModel(t)
+	documentID := postTestDocument(t, m, "5.3.2026", "12,50", "4000", "1910")
+	if _, err := m.tx.Exec(`insert into document_entry
+		(document_id, row_number, account_id, debit, unit_cost_cents,
+		 description) values (?, 3, 4000, 1, 100, '')`,
+		documentID); err != nil {
+		t.Fatal(err)
+	}
+	tb := m.GetTrialBalance(ReportFilter{})
+	if tb.IsBalanced || tb.Difference != "1,00" {
+		t.Errorf("IsBalanced %v, Difference %q, want false and 1,00",
+			tb.IsBalanced, tb.Difference)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	sq "github.com/Masterminds/squirrel"
@@ -23,9 +24,13 @@ type User struct {
 	FullName        string
 	PermissionLevel int
 	IsAdmin         bool
+	IsPending       bool
 	IsMatch         bool
+	IBAN            string
 }
 
+var ErrPendingUser = errors.New("User is waiting for approval")
+
 func getPrivateSessionUser() User {
 	return User{UserID: 0, PermissionLevel: AdminPermission, IsAdmin: true}
 }
@@ -50,6 +55,15 @@ func getUserIDByAuth(tx *sql.Tx, authProvider, authUserID string) (userID int64)
 	return
 }
 
+// userIDOrNil maps the private session user to NULL since it has no
+// row in the user table.
+func userIDOrNil(userID int64) interface{} {
+	if userID == 0 {
+		return nil
+	}
+	return userID
+}
+
 func (m *Model) Forbidden() {
 	m.isErr(errors.New("Forbidden"))
 }
@@ -71,14 +85,15 @@ func (m *Model) isAdminOrUser(userID int64) bool {
 }
 
 func selectUser() sq.SelectBuilder {
-	return sq.Select("user_id, full_name, permission_level").
+	return sq.Select("user_id, full_name, permission_level, pending, iban").
 		From("user").
 		OrderBy("lower(full_name)")
 }
 
 func scanUser(rows sq.RowScanner) (User, error) {
 	var user User
-	err := rows.Scan(&user.UserID, &user.FullName, &user.PermissionLevel)
+	err := rows.Scan(&user.UserID, &user.FullName, &user.PermissionLevel,
+		&user.IsPending, &user.IBAN)
 	user.IsAdmin = (user.PermissionLevel >= AdminPermission)
 	return user, err
 }
@@ -93,7 +108,25 @@ func (m *Model) GetUsers(matchUserID int64) []User {
 	if !m.isAdmin() {
 		return noUsers
 	}
-	rows, err := selectUser().RunWith(m.tx).Query()
+	users := m.usersFromSelect(selectUser().Where("pending = 0"))
+	for i := range users {
+		users[i].IsMatch = (matchUserID != 0 &&
+			users[i].UserID == matchUserID)
+	}
+	return users
+}
+
+func (m *Model) GetPendingUsers() []User {
+	var noUsers []User
+	if !m.isAdmin() {
+		return noUsers
+	}
+	return m.usersFromSelect(selectUser().Where("pending = 1"))
+}
+
+func (m *Model) usersFromSelect(q sq.SelectBuilder) []User {
+	var noUsers []User
+	rows, err := q.RunWith(m.tx).Query()
 	if m.isErr(err) {
 		return noUsers
 	}
@@ -104,9 +137,11 @@ func (m *Model) GetUsers(matchUserID int64) []User {
 		if m.isErr(err) {
 			return noUsers
 		}
-		user.IsMatch = (matchUserID != 0 && user.UserID == matchUserID)
 		users = append(users, user)
 	}
+	if m.isErr(rows.Err()) {
+		return noUsers
+	}
 	sort.SliceStable(users, func(i, j int) bool {
 		return strings.ToLower(users[i].FullName) <
 			strings.ToLower(users[j].FullName)
@@ -114,10 +149,37 @@ func (m *Model) GetUsers(matchUserID int64) []User {
 	return users
 }
 
-func insertUser(tx *sql.Tx, fullName string) (userID int64, err error) {
-	permissionLevel := NormalPermission
+// ApproveUser takes a pending user out of the approval queue. An
+// approved user can see and create their own documents, a rejected
+// user is left deactivated.
+func (m *Model) ApproveUser(userID int64, approve bool) {
+	if !m.isAdmin() {
+		return
+	}
+	permissionLevel := NoPermission
+	if approve {
+		permissionLevel = NormalPermission
+	}
+	m.audit(AuditPermission, auditUserID(userID), "permission_level",
+		"pending", strconv.Itoa(permissionLevel))
+	_, err := sq.Update("user").SetMap(sq.Eq{
+		"permission_level": permissionLevel,
+		"pending":          false,
+	}).Where(sq.Eq{"user_id": userID, "pending": true}).
+		RunWith(m.tx).Exec()
+	m.isErr(err)
+}
+
+func insertUser(tx *sql.Tx, fullName string,
+	autoApprove bool) (userID int64, err error) {
+	permissionLevel := NoPermission
+	pending := true
 	if countUsers(tx) == 0 {
 		permissionLevel = AdminPermission
+		pending = false
+	} else if autoApprove {
+		permissionLevel = NormalPermission
+		pending = false
 	}
 	userID, err = getNewUserID(tx)
 	if err != nil {
@@ -127,6 +189,7 @@ func insertUser(tx *sql.Tx, fullName string) (userID int64, err error) {
 		"user_id":          userID,
 		"full_name":        fullName,
 		"permission_level": permissionLevel,
+		"pending":          pending,
 	}).RunWith(tx).Exec(); err != nil {
 		return 0, err
 	}
@@ -158,8 +221,11 @@ func hashAuthUserID(authProvider, authUserID string) string {
 	return fmt.Sprintf("%x", hasher.Sum(nil))
 }
 
-func GetOrPutUser(authProvider, authUserID,
-	fullName string) (userID int64, err error) {
+// GetOrPutUser returns the user with the given login identity,
+// creating one if needed. New users other than the very first one are
+// left waiting for admin approval unless autoApprove is set.
+func GetOrPutUser(authProvider, authUserID, fullName string,
+	autoApprove bool) (userID int64, err error) {
 	tx, err := db.Begin()
 	if err != nil {
 		return 0, err
@@ -167,7 +233,7 @@ func GetOrPutUser(authProvider, authUserID,
 	authUserID = hashAuthUserID(authProvider, authUserID)
 	userID = getUserIDByAuth(tx, authProvider, authUserID)
 	if userID == 0 {
-		if userID, err = insertUser(tx, fullName); err != nil {
+		if userID, err = insertUser(tx, fullName, autoApprove); err != nil {
 			return 0, err
 		}
 		err = insertUserAuth(tx, userID, authProvider, authUserID)
@@ -182,3 +248,167 @@ func GetOrPutUser(authProvider, authUserID,
 	}
 	return userID, err
 }
+
+// LinkUserAuth adds another login identity to an existing user so
+// that logging in with either identity gives the same user. A user
+// has at most one login with each provider, and an existing one is
+// never replaced.
+func LinkUserAuth(userID int64, authProvider, authUserID string) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	authUserID = hashAuthUserID(authProvider, authUserID)
+	oldUserID := getUserIDByAuth(tx, authProvider, authUserID)
+	if oldUserID == userID {
+		return tx.Commit()
+	}
+	if oldUserID != 0 {
+		return errors.New("Login is already linked to another user")
+	}
+	var count int
+	if err = sq.Select("count(*)").From("user_auth").Where(sq.Eq{
+		"user_id":       userID,
+		"auth_provider": authProvider,
+	}).RunWith(tx).QueryRow().Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("User already has a %s login", authProvider)
+	}
+	if err = insertUserAuth(tx, userID, authProvider, authUserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PutUserIBAN sets the bank account to which the current user's
+// expenses are reimbursed.
+func (m *Model) PutUserIBAN(iban string) {
+	iban = normalizeIBAN(iban)
+	if iban != "" && !IsValidIBAN(iban) {
+		m.isErr(fmt.Errorf("Invalid IBAN: %q", iban))
+		return
+	}
+	m.audit(AuditUser, auditUserID(m.user.UserID), "iban",
+		m.user.IBAN, iban)
+	_, err := sq.Update("user").Set("iban", iban).
+		Where(sq.Eq{"user_id": m.user.UserID}).RunWith(m.tx).Exec()
+	if m.isErr(err) {
+		return
+	}
+	m.user.IBAN = iban
+}
+
+// GetUserAuthProviders lists the login providers linked to the
+// current user.
+func (m *Model) GetUserAuthProviders() []string {
+	providers := []string{}
+	rows, err := sq.Select("auth_provider").From("user_auth").
+		Where(sq.Eq{"user_id": m.user.UserID}).
+		OrderBy("auth_provider").RunWith(m.tx).Query()
+	if m.isErr(err) {
+		return providers
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var provider string
+		if m.isErr(rows.Scan(&provider)) {
+			return providers
+		}
+		providers = append(providers, provider)
+	}
+	m.isErr(rows.Err())
+	return providers
+}
+
+// MergeUsers moves the documents, logins and audit log entries of one
+// user to another and removes the first user. The merged user keeps
+// the higher of the two permission levels, and the bank account of the
+// first user if it has none. Users that both have a login with the
+// same provider cannot be merged, since a user has only one login with
+// each provider.
+func (m *Model) MergeUsers(fromUserID, intoUserID int64) {
+	if !m.isAdmin() {
+		return
+	}
+	if fromUserID == intoUserID {
+		m.isErr(errors.New("Cannot merge a user with itself"))
+		return
+	}
+	if fromUserID == m.user.UserID {
+		m.isErr(errors.New("Cannot merge away the current user"))
+		return
+	}
+	fromUser, err := m.getUserByID(fromUserID)
+	if m.isErr(err) {
+		return
+	}
+	intoUser, err := m.getUserByID(intoUserID)
+	if m.isErr(err) {
+		return
+	}
+	var provider string
+	err = sq.Select("auth_provider").From("user_auth").
+		Where(sq.Eq{"user_id": fromUserID}).
+		Where("auth_provider in (select auth_provider from user_auth where user_id = ?)",
+			intoUserID).
+		Limit(1).RunWith(m.tx).QueryRow().Scan(&provider)
+	if err == nil {
+		m.isErr(fmt.Errorf("Both users have a %s login", provider))
+		return
+	}
+	if err != sql.ErrNoRows {
+		m.isErr(err)
+		return
+	}
+	// Closed documents are updated too, bypassing ErrDocumentClosed on
+	// purpose: both users are the same person, so the document keeps
+	// who paid and who closed it and its entries stay as they were.
+	// Leaving them would point them at a user that no longer exists.
+	for _, column := range []string{"paid_user_id", "closed_user_id"} {
+		_, err = sq.Update("document").Set(column, intoUserID).
+			Where(sq.Eq{column: fromUserID}).RunWith(m.tx).Exec()
+		if m.isErr(err) {
+			return
+		}
+	}
+	for _, table := range []string{"user_auth", "audit_log"} {
+		_, err = sq.Update(table).Set("user_id", intoUserID).
+			Where(sq.Eq{"user_id": fromUserID}).RunWith(m.tx).Exec()
+		if m.isErr(err) {
+			return
+		}
+	}
+	if intoUser.IBAN == "" && fromUser.IBAN != "" {
+		m.audit(AuditUser, auditUserID(intoUserID), "iban",
+			"", fromUser.IBAN)
+		_, err = sq.Update("user").Set("iban", fromUser.IBAN).
+			Where(sq.Eq{"user_id": intoUserID}).RunWith(m.tx).Exec()
+		if m.isErr(err) {
+			return
+		}
+	}
+	m.audit(AuditPermission, auditUserID(fromUserID), "merged_into",
+		"", auditUserID(intoUserID))
+	if fromUser.PermissionLevel > intoUser.PermissionLevel {
+		m.audit(AuditPermission, auditUserID(intoUserID),
+			"permission_level",
+			strconv.Itoa(intoUser.PermissionLevel),
+			strconv.Itoa(fromUser.PermissionLevel))
+		_, err = sq.Update("user").
+			Set("permission_level", fromUser.PermissionLevel).
+			Where(sq.Eq{"user_id": intoUserID}).RunWith(m.tx).Exec()
+		if m.isErr(err) {
+			return
+		}
+	}
+	_, err = sq.Delete("user").Where(sq.Eq{"user_id": fromUserID}).
+		RunWith(m.tx).Exec()
+	m.isErr(err)
+}
@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"math/big"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -101,3 +103,48 @@ func centsFromAmount(amount string) (int64, error) {
 	cents = (euros * 100) + cents
 	return int64(cents), nil
 }
+
+// SignedCentsFromAmount is like centsFromAmount but also takes negative
+// amounts, such as budgeted expenses entered with a minus sign.
+func SignedCentsFromAmount(amount string) (int64, error) {
+	amount = regexp.MustCompile(`\s+`).ReplaceAllString(amount, "")
+	if strings.HasPrefix(amount, "-") {
+		if amount == "-" {
+			return 0, fmt.Errorf("Invalid amount: %q", amount)
+		}
+		cents, err := centsFromAmount(amount[1:])
+		return -cents, err
+	}
+	return centsFromAmount(amount)
+}
+
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(regexp.MustCompile(`\s+`).ReplaceAllString(iban, ""))
+}
+
+// IsValidIBAN checks the ISO 13616 mod-97 check digits of a
+// normalized IBAN.
+func IsValidIBAN(iban string) bool {
+	if !regexp.MustCompile(`^[A-Z]{2}\d{2}[A-Z0-9]{1,30}$`).MatchString(iban) {
+		return false
+	}
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' && c <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(c-'A') + 10))
+		} else {
+			digits.WriteRune(c)
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// isValidBIC checks the form of an ISO 9362 bank identifier code: bank,
+// country and location, optionally followed by a branch.
+func isValidBIC(bic string) bool {
+	return regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`).MatchString(bic)
+}
@@ -0,0 +1,71 @@
+package model
+
+import "testing"
+
+func TestIsValidIBAN(t *testing.T) {
+	tests := []struct {
+		iban string
+		want bool
+	}{
+		{"FI2112345600000785", true},
+		{"FI 2112 3456 0000 0785", true},
+		{"fi2112345600000785", true},
+		{"GB82WEST12345698765432", true},
+		{"FI2112345600000786", false},
+		{"FI2212345600000785", false},
+		{"FI21", false},
+		{"2112345600000785", false},
+		{"FI21-1234-5600-0007-85", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := IsValidIBAN(normalizeIBAN(test.iban)); got != test.want {
+			t.Errorf("IsValidIBAN(%q) = %v, want %v",
+				test.iban, got, test.want)
+		}
+	}
+}
+
+func TestIsValidBIC(t *testing.T) {
+	tests := []struct {
+		bic  string
+		want bool
+	}{
+		{"NDEAFIHH", true},
+		{"OKOYFIHHXXX", true},
+		{"DABAFIHX", true},
+		{"NDEAFIH", false},
+		{"NDEAFIHHX", false},
+		{"NDE4FIHH", false},
+		{"ndeafihh", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := isValidBIC(test.bic); got != test.want {
+			t.Errorf("isValidBIC(%q) = %v, want %v",
+				test.bic, got, test.want)
+		}
+	}
+}
+
+func TestPutSettingsBankDetails(t *testing.T) {
+	tests := []struct {
+		iban, bic string
+		ok        bool
+	}{
+		{"FI21 1234 5600 0007 85", "ndeafihh", true},
+		{"", "", true},
+		{"FI2112345600000786", "NDEAFIHH", false},
+		{"FI2112345600000785", "NDEA", false},
+	}
+	for _, test := range tests {
+		t.Run(test.iban+" "+test.bic, func(t *testing.T) {
+			m := newTestModel(t)
+			m.PutSettings(Settings{OrgIBAN: test.iban, OrgBIC: test.bic})
+			if ok := (m.Err == nil); ok != test.ok {
+				t.Errorf("PutSettings(%q, %q): %v",
+					test.iban, test.bic, m.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package reports
+
+import (
+	"strconv"
+
+	"github.com/lassik/massikone/model"
+)
+
+// AccountStatement lists the entries of one account with the running
+// balance between its opening and closing balances.
+func AccountStatement(m *model.Model, getWriter GetWriter, opts Options,
+	accountID int) error {
+	const numberWidth = 2
+	const dateWidth = 3
+	const descriptionWidth = 10
+	statement := m.GetAccountStatement(accountID, opts.ReportFilter)
+	if statement == nil {
+		return nil
+	}
+	balanceRow := func(text, balance string) []cell {
+		return []cell{
+			cell{width: numberWidth},
+			cell{width: dateWidth},
+			cell{width: numberWidth},
+			cell{width: numberWidth},
+			cell{
+				text:       balance,
+				bold:       true,
+				width:      numberWidth,
+				rightAlign: true,
+			},
+			cell{text: text, bold: true, width: descriptionWidth},
+		}
+	}
+	doc := document{
+		title: "Tiliote " + strconv.Itoa(accountID) + " " +
+			statement.Account.Title,
+		filename: "tiliote " + strconv.Itoa(accountID),
+		orgName:  m.GetSettings().OrgShortName,
+		period:   m.ReportPeriod(opts.ReportFilter),
+		headerRow: []cell{
+			cell{text: "Tosite", width: numberWidth},
+			cell{text: "Pvm", width: dateWidth, rightAlign: true},
+			cell{text: "Debet", width: numberWidth, rightAlign: true},
+			cell{text: "Kredit", width: numberWidth, rightAlign: true},
+			cell{text: "Saldo", width: numberWidth, rightAlign: true},
+			cell{text: "Selite", width: descriptionWidth},
+		},
+	}
+	withProject(m, &doc, opts.ProjectID)
+	doc.rows = append(doc.rows,
+		balanceRow("Alkusaldo", statement.OpeningBalance))
+	for _, entry := range statement.Entries {
+		doc.rows = append(doc.rows, []cell{
+			cell{
+				text:  entry.DocumentID,
+				width: numberWidth,
+				href:  documentHref(entry.DocumentID),
+			},
+			cell{
+				text:       entry.PaidDateFi,
+				width:      dateWidth,
+				rightAlign: true,
+			},
+			cell{
+				text:       entry.DebitAmount,
+				width:      numberWidth,
+				rightAlign: true,
+			},
+			cell{
+				text:       entry.CreditAmount,
+				width:      numberWidth,
+				rightAlign: true,
+			},
+			cell{
+				text:       entry.BalanceAfter,
+				width:      numberWidth,
+				rightAlign: true,
+			},
+			cell{text: entry.Description, width: descriptionWidth},
+		})
+	}
+	closingRow := balanceRow("Loppusaldo", statement.ClosingBalance)
+	closingRow[2] = cell{
+		text:       statement.TotalDebit,
+		bold:       true,
+		width:      numberWidth,
+		rightAlign: true,
+	}
+	closingRow[3] = cell{
+		text:       statement.TotalCredit,
+		bold:       true,
+		width:      numberWidth,
+		rightAlign: true,
+	}
+	doc.rows = append(doc.rows, closingRow)
+	return writeDocument(m, doc, getWriter, opts)
+}
@@ -0,0 +1,54 @@
+package reports
+
+import (
+	"strconv"
+
+	"github.com/lassik/massikone/model"
+)
+
+func AuditLog(m *model.Model, getWriter GetWriter, opts Options) error {
+	const timeWidth = 3
+	const userWidth = 3
+	const objectWidth = 3
+	const valueWidth = 6
+	doc := document{
+		title:    "Muutosloki",
+		filename: "muutosloki",
+		orgName:  m.GetSettings().OrgShortName,
+		period:   m.ReportPeriod(opts.ReportFilter),
+		headerRow: []cell{
+			cell{text: "Aika", width: timeWidth},
+			cell{text: "Käyttäjä", width: userWidth},
+			cell{text: "Kohde", width: objectWidth},
+			cell{text: "Ennen", width: valueWidth},
+			cell{text: "Jälkeen", width: valueWidth},
+		},
+	}
+	for _, entry := range m.GetAuditLogOfPeriod(opts.ReportFilter) {
+		href := ""
+		switch entry.ObjectType {
+		case model.AuditDocument, model.AuditDocumentEntry,
+			model.AuditDocumentImage:
+			href = documentHref(entry.ObjectID)
+		case model.AuditProject:
+			href = projectHref(entry.ObjectID)
+		case model.AuditAccount:
+			if accountID, err := strconv.Atoi(entry.ObjectID); err == nil {
+				href = opts.accountStatementHref(accountID)
+			}
+		}
+		doc.rows = append(doc.rows, []cell{
+			cell{text: entry.ChangedTimeFi, width: timeWidth},
+			cell{text: shorten(entry.UserFullName), width: userWidth},
+			cell{
+				text: entry.ObjectType + " " + entry.ObjectID +
+					" " + entry.Field,
+				width: objectWidth,
+				href:  href,
+			},
+			cell{text: shorten(entry.OldValue), width: valueWidth},
+			cell{text: shorten(entry.NewValue), width: valueWidth},
+		})
+	}
+	return writeDocument(m, doc, getWriter, opts)
+}
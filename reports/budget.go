@@ -0,0 +1,74 @@
+package reports
+
+import (
+	"github.com/lassik/massikone/model"
+)
+
+// BudgetComparisonPdf compares the budget of the period to the actual
+// income statement.
+func BudgetComparisonPdf(m *model.Model, getWriter GetWriter, periodID int64) error {
+	const titleWidth = 8
+	const amountWidth = 2
+	comparison := m.GetBudgetComparison(periodID)
+	if comparison == nil {
+		return nil
+	}
+	doc := document{
+		title:    "Talousarviovertailu",
+		filename: "talousarviovertailu",
+		orgName:  m.GetSettings().OrgShortName,
+		period:   comparison.Period.String(),
+		headerRow: []cell{
+			cell{text: "Tili", width: titleWidth},
+			cell{text: "Talousarvio", width: amountWidth, rightAlign: true},
+			cell{text: "Toteuma", width: amountWidth, rightAlign: true},
+			cell{text: "Erotus", width: amountWidth, rightAlign: true},
+			cell{text: "%", width: amountWidth, rightAlign: true},
+		},
+	}
+	for _, row := range comparison.Rows {
+		if row.IsHeading() {
+			doc.rows = append(doc.rows, []cell{
+				cell{
+					text:        row.Title,
+					bold:        true,
+					indentLevel: row.NestingLevel,
+					width:       titleWidth + 4*amountWidth,
+				},
+			})
+			continue
+		}
+		title := row.Title
+		if !row.IsTotal {
+			title = row.AccountIDStr + " " + row.Title
+		}
+		doc.rows = append(doc.rows, []cell{
+			cell{text: title, bold: row.IsTotal, width: titleWidth},
+			cell{
+				text:       row.Budget,
+				bold:       row.IsTotal,
+				width:      amountWidth,
+				rightAlign: true,
+			},
+			cell{
+				text:       row.Actual,
+				bold:       row.IsTotal,
+				width:      amountWidth,
+				rightAlign: true,
+			},
+			cell{
+				text:       row.Variance,
+				bold:       row.IsTotal,
+				width:      amountWidth,
+				rightAlign: true,
+			},
+			cell{
+				text:       row.Percent,
+				bold:       row.IsTotal,
+				width:      amountWidth,
+				rightAlign: true,
+			},
+		})
+	}
+	return writePdf(m, doc, getWriter)
+}
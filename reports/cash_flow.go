@@ -0,0 +1,71 @@
+package reports
+
+import (
+	"strings"
+
+	"github.com/lassik/massikone/model"
+)
+
+// CashFlow shows the cash at the start of the period, what came in and
+// went out during it by the type of the counter-account, and the cash
+// at the end.
+func CashFlow(m *model.Model, getWriter GetWriter, opts Options) error {
+	const titleWidth = 8
+	const amountWidth = 2
+	cf := m.GetCashFlow(opts.ReportFilter)
+	if m.Err != nil {
+		return m.Err
+	}
+	doc := document{
+		title:    "Rahoituslaskelma",
+		filename: "rahoituslaskelma",
+		orgName:  m.GetSettings().OrgShortName,
+		period:   cf.Period,
+		headerRow: []cell{
+			cell{width: titleWidth},
+			cell{text: cf.Period, width: amountWidth, rightAlign: true},
+		},
+	}
+	if len(cf.CashAccounts) > 0 {
+		var accounts []string
+		for _, acct := range cf.CashAccounts {
+			accounts = append(accounts, acct.AccountIDStr+" "+acct.Title)
+		}
+		doc.headerRow[0].text = "Kassa- ja pankkitilit: " +
+			strings.Join(accounts, ", ")
+	}
+	row := func(title, amount string, bold bool, indentLevel int) {
+		doc.rows = append(doc.rows, []cell{
+			cell{
+				text:        title,
+				bold:        bold,
+				indentLevel: indentLevel,
+				width:       titleWidth,
+			},
+			cell{
+				text:       amount,
+				bold:       bold,
+				rightAlign: true,
+				width:      amountWidth,
+			},
+		})
+	}
+	if len(cf.CashAccounts) == 0 {
+		row("Kassa- ja pankkitilejä ei ole merkitty asetuksissa.",
+			"", false, 0)
+		return writeDocument(m, doc, getWriter, opts)
+	}
+	row("Kassavarat kauden alussa", cf.OpeningCash, true, 0)
+	row("Rahan lähteet", "", true, 0)
+	for _, inflow := range cf.Inflows {
+		row(inflow.Title, inflow.Amount, false, 1)
+	}
+	row("Rahan lähteet yhteensä", cf.TotalInflow, true, 0)
+	row("Rahan käyttö", "", true, 0)
+	for _, outflow := range cf.Outflows {
+		row(outflow.Title, outflow.Amount, false, 1)
+	}
+	row("Rahan käyttö yhteensä", cf.TotalOutflow, true, 0)
+	row("Kassavarat kauden lopussa", cf.ClosingCash, true, 0)
+	return writeDocument(m, doc, getWriter, opts)
+}
@@ -4,22 +4,26 @@ import (
 	"github.com/lassik/massikone/model"
 )
 
-func ChartOfAccountsPdf(m *model.Model, getWriter GetWriter) {
+func ChartOfAccounts(m *model.Model, getWriter GetWriter, opts Options) error {
 	accounts := m.GetAccountList(false, "")
 	doc := document{
-		orgName:   m.GetSettings().OrgShortName,
-		title:     "Tilikartta",
-		filename:  "tilikartta",
-		period:    "1.1.2018 - 31.12.2018",
-		printDate: "1.12.2018",
+		orgName:  m.GetSettings().OrgShortName,
+		title:    "Tilikartta",
+		filename: "tilikartta",
+		period:   m.ReportPeriod(model.ReportFilter{}),
 	}
 	for _, acct := range accounts {
 		bold := acct.IsHeading()
+		href := ""
+		if !acct.IsHeading() {
+			href = opts.accountStatementHref(acct.AccountID)
+		}
 		thisRow := []cell{
 			cell{
 				text:  acct.AccountIDStr,
 				bold:  bold,
 				width: 1,
+				href:  href,
 			},
 			cell{
 				text:        acct.Title,
@@ -30,5 +34,5 @@ func ChartOfAccountsPdf(m *model.Model, getWriter GetWriter) {
 		}
 		doc.rows = append(doc.rows, thisRow)
 	}
-	writePdf(m, doc, getWriter)
+	return writeDocument(m, doc, getWriter, opts)
 }
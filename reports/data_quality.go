@@ -0,0 +1,70 @@
+package reports
+
+import (
+	"strings"
+
+	"github.com/lassik/massikone/model"
+)
+
+// DataQuality lists the documents that have something missing or
+// inconsistent, one problem per row, and the gaps in document numbers.
+func DataQuality(m *model.Model, getWriter GetWriter, opts Options) error {
+	const numberWidth = 1
+	const dateWidth = 2
+	const descriptionWidth = 6
+	const problemWidth = 9
+	const totalWidth = numberWidth + dateWidth + descriptionWidth +
+		problemWidth
+	dq := m.GetDataQuality()
+	if m.Err != nil {
+		return m.Err
+	}
+	doc := document{
+		title:    "Tietojen laatu",
+		filename: "tietojen-laatu",
+		orgName:  m.GetSettings().OrgShortName,
+		headerRow: []cell{
+			cell{text: "Nro", width: numberWidth},
+			cell{text: "Pvm", width: dateWidth},
+			cell{text: "Selite", width: descriptionWidth},
+			cell{text: "Puute", width: problemWidth},
+		},
+	}
+	for _, document := range dq.Documents {
+		for i, problem := range document.Problems {
+			// The number is on every row so that each row still
+			// makes sense when the report is sorted or filtered as a
+			// spreadsheet.
+			row := []cell{
+				cell{
+					text:  document.DocumentID,
+					width: numberWidth,
+					href:  documentHref(document.DocumentID),
+				},
+				cell{width: dateWidth},
+				cell{width: descriptionWidth},
+				cell{text: problem, width: problemWidth},
+			}
+			if i == 0 {
+				row[1].text = document.PaidDateFi
+				row[2].text = truncateUnicode(document.Description, 40)
+			}
+			doc.rows = append(doc.rows, row)
+		}
+	}
+	if len(dq.Gaps) > 0 {
+		doc.rows = append(doc.rows, []cell{
+			cell{
+				text:  "Puuttuvat tositenumerot: " + strings.Join(dq.Gaps, ", "),
+				bold:  true,
+				width: totalWidth,
+			},
+		})
+	}
+	if !dq.HasProblems {
+		doc.rows = append(doc.rows, []cell{
+			cell{text: "Puutteita ei löytynyt.", width: totalWidth},
+		})
+	}
+	return writeDocument(m, doc, getWriter, opts)
+}
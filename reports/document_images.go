@@ -2,37 +2,48 @@ package reports
 
 import (
 	"fmt"
-	"log"
 	"path"
 
 	"github.com/lassik/massikone/model"
 )
 
-func addDocumentImagesToZip(m *model.Model, getWriter GetWriter) {
-	images, missing := m.GetDocumentsForImages()
+// addDocumentImagesToZip writes the images of the documents of the
+// filter one at a time, so that only one of them is held in memory.
+func addDocumentImagesToZip(m *model.Model, getWriter GetWriter,
+	filter model.ReportFilter) error {
+	images, missing := m.GetDocumentsForImages(filter)
+	if m.Err != nil {
+		return m.Err
+	}
 	for _, image := range images {
-		if image["image_id"] != nil {
-			w, err := getWriter(
-				"image/"+path.Ext(image["image_id"].(string)),
-				fmt.Sprintf("tositteet/tosite-%03d-%d-%s%s",
-					image["document_id"].(int),
-					image["document_image_num"].(int),
-					slug(image["description"].(string)),
-					path.Ext(image["image_id"].(string))))
-			if err != nil {
-				log.Fatal(err)
-			}
-			_, err = w.Write(image["image_data"].([]byte))
-			if err != nil {
-				log.Fatal(err)
-			}
+		imageData, mimeType, err := m.GetImage(image.ImageID)
+		if err != nil {
+			return fmt.Errorf("Image %s of document %d: %v",
+				image.ImageID, image.DocumentID, err)
+		}
+		w, err := getWriter(mimeType,
+			fmt.Sprintf("tositteet/tosite-%03d-%d-%s%s",
+				image.DocumentID,
+				image.DocumentImageNum,
+				slug(image.Description),
+				path.Ext(image.ImageID)))
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(imageData); err != nil {
+			return err
 		}
 	}
 	if len(missing) > 0 {
 		w, err := getWriter("text/plain", "tositteet/puuttuvat.txt")
-		check(err)
-		for _, documentId := range missing {
-			fmt.Fprintf(w, "#%d\r\n", documentId)
+		if err != nil {
+			return err
+		}
+		for _, documentID := range missing {
+			if _, err = fmt.Fprintf(w, "#%d\r\n", documentID); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
 }
@@ -0,0 +1,258 @@
+package reports
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lassik/massikone/model"
+)
+
+const (
+	FormatPdf  = "pdf"
+	FormatCsv  = "csv"
+	FormatXlsx = "xlsx"
+	FormatHtml = "html"
+)
+
+// UserError is a problem in the data that keeps a report from being
+// made and that the user can fix. The message is shown to them.
+type UserError string
+
+func (e UserError) Error() string {
+	return string(e)
+}
+
+// Options selects what a tabular report covers and the format it is
+// written in.
+type Options struct {
+	Format string
+	model.ReportFilter
+	// Carry the debit and credit totals from page to page in PDF.
+	CarryForward bool
+	// Write PDF/A of this part instead of plain PDF if nonzero.
+	PdfA int
+}
+
+func IsValidFormat(format string) bool {
+	switch format {
+	case "", FormatPdf, FormatCsv, FormatXlsx, FormatHtml:
+		return true
+	}
+	return false
+}
+
+// query gives the query string that asks for the same report in
+// another format.
+func (opts Options) query(format string) string {
+	q := url.Values{}
+	q.Set("format", format)
+	if opts.ProjectID != "" {
+		q.Set("projekti", opts.ProjectID)
+	}
+	if len(opts.Accounts) > 0 {
+		q.Set("tilit", model.FormatAccountRanges(opts.Accounts))
+	}
+	if opts.StartDateFi != "" {
+		q.Set("alkaen", opts.StartDateFi)
+	}
+	if opts.EndDateFi != "" {
+		q.Set("asti", opts.EndDateFi)
+	}
+	if documents := opts.DocumentRange(); documents != "" {
+		q.Set("tositteet", documents)
+	}
+	if opts.CarryForward && format == FormatPdf {
+		q.Set("siirto", "1")
+	}
+	if opts.PdfA != 0 && format == FormatPdf {
+		q.Set("pdfa", strconv.Itoa(opts.PdfA))
+	}
+	return "?" + q.Encode()
+}
+
+// accountHref links to the on-screen ledger of one account, keeping
+// the rest of the filter.
+func (opts Options) accountHref(accountID int) string {
+	ledgerOpts := Options{ReportFilter: opts.ReportFilter}
+	ledgerOpts.Accounts = []model.AccountRange{
+		{Start: accountID, Limit: accountID + 1},
+	}
+	return "/raportti/paakirja" + ledgerOpts.query(FormatHtml)
+}
+
+// accountStatementHref links to the on-screen statement of one
+// account over the same dates.
+func (opts Options) accountStatementHref(accountID int) string {
+	statementOpts := Options{ReportFilter: model.ReportFilter{
+		ProjectID:   opts.ProjectID,
+		StartDateFi: opts.StartDateFi,
+		EndDateFi:   opts.EndDateFi,
+	}}
+	return "/raportti/tiliote/" + strconv.Itoa(accountID) +
+		statementOpts.query(FormatHtml)
+}
+
+func documentHref(documentID string) string {
+	return "/tosite/" + documentID
+}
+
+// writeDocument writes the document in the format chosen in opts.
+func writeDocument(m *model.Model, doc document, getWriter GetWriter,
+	opts Options) error {
+	doc.filter = opts.ReportFilter
+	doc.carryForward = opts.CarryForward
+	doc.pdfA = opts.PdfA
+	switch opts.Format {
+	case FormatHtml:
+		return writeHtml(m, doc, getWriter, opts)
+	case FormatCsv:
+		return writeCsv(m, doc, getWriter)
+	case FormatXlsx:
+		return writeXlsx(m, doc, getWriter)
+	default:
+		return writePdf(m, doc, getWriter)
+	}
+}
+
+// tableFromDocument flattens the header and rows of the document into
+// plain text for spreadsheets. Indentation is dropped.
+func tableFromDocument(doc document) [][]string {
+	var table [][]string
+	for _, row := range append([][]cell{doc.headerRow}, doc.rows...) {
+		if len(row) == 0 {
+			continue
+		}
+		texts := make([]string, len(row))
+		for i, c := range row {
+			texts[i] = c.text
+		}
+		table = append(table, texts)
+	}
+	return table
+}
+
+func writeCsv(m *model.Model, doc document, getWriter GetWriter) error {
+	w, err := getWriter("text/csv; charset=utf-8",
+		generateFilename(m, doc.filter, doc.filename)+".csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+	return cw.WriteAll(tableFromDocument(doc))
+}
+
+var xlsxAmount = regexp.MustCompile(`^-?\d+,\d\d$`)
+
+func xlsxColumnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+func xlsxSheetName(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, title)
+	return truncateUnicode(title, 31)
+}
+
+func xlsxEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeXlsx writes the document as a minimal Office Open XML
+// workbook with a single sheet. Amounts become numbers so that they
+// can be summed in the spreadsheet.
+func writeXlsx(m *model.Model, doc document, getWriter GetWriter) error {
+	w, err := getWriter(
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		generateFilename(m, doc.filter, doc.filename)+".xlsx")
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	addFile := func(name, contents string) {
+		if err != nil {
+			return
+		}
+		var fw io.Writer
+		if fw, err = zw.Create(name); err == nil {
+			_, err = io.WriteString(fw, xml.Header+contents)
+		}
+	}
+	addFile("[Content_Types].xml",
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`+
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`+
+			`<Default Extension="xml" ContentType="application/xml"/>`+
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`+
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`+
+			`</Types>`)
+	addFile("_rels/.rels",
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>`+
+			`</Relationships>`)
+	addFile("xl/_rels/workbook.xml.rels",
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>`+
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`+
+			`</Relationships>`)
+	addFile("xl/workbook.xml",
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`+
+			`<sheets><sheet name="`+xlsxEscape(xlsxSheetName(doc.title))+`" sheetId="1" r:id="rId1"/></sheets>`+
+			`</workbook>`)
+	// Style 1 shows a number with two decimals and a thousands
+	// separator (built-in format 4) in the locale of the spreadsheet.
+	addFile("xl/styles.xml",
+		`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+			`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>`+
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>`+
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`+
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`+
+			`<cellXfs count="2">`+
+			`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`+
+			`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`+
+			`</cellXfs>`+
+			`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>`+
+			`</styleSheet>`)
+	var sheet strings.Builder
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range tableFromDocument(doc) {
+		fmt.Fprintf(&sheet, `<row r="%d">`, i+1)
+		for j, text := range row {
+			if text == "" {
+				continue
+			}
+			ref := fmt.Sprintf("%s%d", xlsxColumnName(j), i+1)
+			if xlsxAmount.MatchString(text) {
+				fmt.Fprintf(&sheet, `<c r="%s" s="1" t="n"><v>%s</v></c>`, ref,
+					strings.Replace(text, ",", ".", 1))
+			} else {
+				fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`,
+					ref, xlsxEscape(text))
+			}
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+	addFile("xl/worksheets/sheet1.xml", sheet.String())
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -2,32 +2,159 @@ package reports
 
 import (
 	"archive/zip"
+	"fmt"
 	"io"
-	"log"
 	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/lassik/massikone/model"
 )
 
-func FullStatementZip(m *model.Model, getWriter GetWriter) {
-	zipFilename := generateFilename(m, "tilinpäätos")
+// wrapText splits a line of text into lines of at most maxRunes
+// characters at spaces, since report cells do not wrap by themselves.
+func wrapText(line string, maxRunes int) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(line) {
+		if current != "" &&
+			utf8.RuneCountInString(current+" "+word) > maxRunes {
+			lines = append(lines, current)
+			current = ""
+		}
+		if current != "" {
+			current += " "
+		}
+		current += word
+	}
+	return append(lines, current)
+}
+
+// textDocument makes a report page out of free text, one row per line.
+func textDocument(m *model.Model, title, text string) document {
+	doc := document{
+		title:    title,
+		filename: title,
+		orgName:  m.GetSettings().OrgShortName,
+	}
+	if period := m.GetPeriod(0); period != nil {
+		doc.period = period.String()
+	}
+	for _, paragraph := range strings.Split(text, "\n") {
+		for _, line := range wrapText(paragraph, 100) {
+			doc.rows = append(doc.rows, []cell{
+				cell{text: line, width: 1},
+			})
+		}
+	}
+	return doc
+}
+
+func notesPdf(m *model.Model, getWriter GetWriter, pdfA int) error {
+	doc := textDocument(m, "Liitetiedot", m.GetSettings().StatementNotes)
+	doc.pdfA = pdfA
+	return writePdf(m, doc, getWriter)
+}
+
+func signaturesPdf(m *model.Model, getWriter GetWriter, pdfA int) error {
+	doc := textDocument(m, "Allekirjoitukset",
+		m.GetSettings().StatementSignatures)
+	doc.pdfA = pdfA
+	return writePdf(m, doc, getWriter)
+}
+
+// indexPdf lists the files of the full statement package.
+func indexPdf(m *model.Model, getWriter GetWriter, filenames []string,
+	pdfA int) error {
+	const numberWidth = 1
+	const filenameWidth = 12
+	doc := textDocument(m, "Sisällysluettelo", "")
+	doc.rows = nil
+	doc.pdfA = pdfA
+	for i, filename := range filenames {
+		doc.rows = append(doc.rows, []cell{
+			cell{
+				text:       strconv.Itoa(i + 1),
+				width:      numberWidth,
+				rightAlign: true,
+			},
+			cell{text: filename, width: filenameWidth},
+		})
+	}
+	return writePdf(m, doc, getWriter)
+}
+
+// flusher is implemented by HTTP response writers that can send what
+// has been written so far to the client.
+type flusher interface {
+	Flush()
+}
+
+// FullStatementZip bundles the financial statement, the books, the
+// notes and signature pages, the receipts and the document images of
+// the latest period into one archive, with an index of the files in
+// it. The archive is sent to the client one file at a time. If
+// something goes wrong after that has started, the error is also
+// written into the archive as virhe.txt. If pdfA is nonzero, the
+// reports are written as PDF/A of that part.
+func FullStatementZip(m *model.Model, getWriter GetWriter, pdfA int) error {
+	opts := Options{
+		ReportFilter: m.StatementFilter(model.ReportFilter{}),
+		PdfA:         pdfA,
+	}
+	zipFilename := generateFilename(m, opts.ReportFilter, "tilinpäätös")
 	zipBasename := path.Base(zipFilename)
 	outerWriter, err := getWriter("application/zip", zipFilename+".zip")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	//zipBuf := new(bytes.Buffer)
-	zipWriter := zip.NewWriter(outerWriter) //zip.NewWriter(zipBuf)
+	zipWriter := zip.NewWriter(outerWriter)
+	var filenames []string
 	writeToZip := func(_, filename string) (io.Writer, error) {
+		if err := zipWriter.Flush(); err != nil {
+			return nil, err
+		}
+		if f, ok := outerWriter.(flusher); ok {
+			f.Flush()
+		}
+		filenames = append(filenames, filename)
 		return zipWriter.Create(zipBasename + "/" + filename)
 	}
-	GeneralJournalPdf(m, writeToZip)
-	ChartOfAccountsPdf(m, writeToZip)
-	addDocumentImagesToZip(m, writeToZip)
-	err = zipWriter.Close()
+	tabular := func(generate func(*model.Model, GetWriter, Options) error) func() error {
+		return func() error {
+			return generate(m, writeToZip, opts)
+		}
+	}
+	steps := []func() error{
+		tabular(IncomeStatement),
+		tabular(IncomeStatementDetailed),
+		tabular(BalanceSheet),
+		tabular(BalanceSheetDetailed),
+		func() error { return notesPdf(m, writeToZip, pdfA) },
+		func() error { return signaturesPdf(m, writeToZip, pdfA) },
+		tabular(GeneralJournal),
+		tabular(GeneralLedger),
+		tabular(TrialBalance),
+		tabular(ChartOfAccounts),
+		tabular(receiptsPdfParts),
+		func() error {
+			return addDocumentImagesToZip(m, writeToZip, opts.ReportFilter)
+		},
+		func() error { return indexPdf(m, writeToZip, filenames, pdfA) },
+	}
+	for _, step := range steps {
+		if err = step(); err != nil {
+			break
+		}
+	}
 	if err != nil {
-		log.Fatal(err)
+		if w, zipErr := writeToZip("text/plain", "virhe.txt"); zipErr == nil {
+			fmt.Fprintf(w, "Arkiston teko keskeytyi: %v\r\n", err)
+		}
 	}
-	//io.Copy(outerWriter, zipBuf)
-
+	if closeErr := zipWriter.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
@@ -0,0 +1,114 @@
+package reports
+
+import (
+	"io"
+
+	"github.com/hoisie/mustache"
+
+	"github.com/lassik/massikone/model"
+)
+
+// The report page is a mustache template like the other pages, packed
+// into templates.go from the templates directory.
+var htmlTemplate = getTemplate("/report.mustache")
+
+func getTemplate(filename string) *mustache.Template {
+	tmplString := templates[filename].Contents
+	if tmplString == "" {
+		panic("Template not found")
+	}
+	tmpl, err := mustache.ParseString(tmplString)
+	if err != nil {
+		panic(err)
+	}
+	return tmpl
+}
+
+type htmlCell struct {
+	Text       string
+	Bold       bool
+	RightAlign bool
+	ColSpan    int
+	Indent     float64
+	Href       string
+	HasHref    bool
+}
+
+// htmlRowCells wraps the cells of a row, since a mustache section
+// cannot loop over a slice that is itself an element of a loop.
+type htmlRowCells struct {
+	Cells []htmlCell
+}
+
+type htmlLink struct {
+	Title string
+	Href  string
+}
+
+// htmlRow gives each cell its own column. The widths only make sense
+// on paper, so the last cell of a short row spans the rest instead.
+func htmlRow(row []cell, columnCount int) []htmlCell {
+	cells := make([]htmlCell, len(row))
+	for i, c := range row {
+		cells[i] = htmlCell{
+			Text:       c.text,
+			Bold:       c.bold,
+			RightAlign: c.rightAlign,
+			ColSpan:    1,
+			Indent:     0.5 + float64(c.indentLevel),
+			Href:       c.href,
+			HasHref:    c.href != "",
+		}
+	}
+	if len(cells) > 0 {
+		cells[len(cells)-1].ColSpan = columnCount - len(cells) + 1
+	}
+	return cells
+}
+
+// writeHtml shows the document as a web page with links to the same
+// report in the downloadable formats. Cells with a link let the
+// reader drill down to accounts and documents.
+func writeHtml(m *model.Model, doc document, getWriter GetWriter, opts Options) error {
+	w, err := getWriter("text/html; charset=utf-8",
+		generateFilename(m, doc.filter, doc.filename)+".html")
+	if err != nil {
+		return err
+	}
+	columnCount := len(doc.headerRow)
+	for _, row := range doc.rows {
+		if len(row) > columnCount {
+			columnCount = len(row)
+		}
+	}
+	var rows []htmlRowCells
+	for _, row := range doc.rows {
+		rows = append(rows, htmlRowCells{htmlRow(row, columnCount)})
+	}
+	var headerRow []htmlRowCells
+	if len(doc.headerRow) > 0 {
+		headerRow = []htmlRowCells{{htmlRow(doc.headerRow, columnCount)}}
+	}
+	formats := []htmlLink{{"PDF", opts.query(FormatPdf)}}
+	if len(carryColumns(doc)) > 0 {
+		carryOpts := opts
+		carryOpts.CarryForward = true
+		formats = append(formats,
+			htmlLink{"PDF siirroin", carryOpts.query(FormatPdf)})
+	}
+	pdfAOpts := opts
+	pdfAOpts.PdfA = PdfA2
+	formats = append(formats,
+		htmlLink{"PDF/A", pdfAOpts.query(FormatPdf)},
+		htmlLink{"CSV", opts.query(FormatCsv)},
+		htmlLink{"XLSX", opts.query(FormatXlsx)})
+	_, err = io.WriteString(w, htmlTemplate.Render(map[string]interface{}{
+		"Title":     doc.title,
+		"OrgName":   doc.orgName,
+		"Period":    doc.period,
+		"HeaderRow": headerRow,
+		"Rows":      rows,
+		"Formats":   formats,
+	}))
+	return err
+}
@@ -0,0 +1,143 @@
+package reports
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/boombuler/barcode/code128"
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/lassik/massikone/model"
+)
+
+var finnishIBAN = regexp.MustCompile(`^FI(\d{16})$`)
+
+// virtualBarcode returns the version 4 Finnish bank barcode
+// (virtuaaliviivakoodi) for a payment to a Finnish IBAN, or "" if
+// one cannot be made.
+func virtualBarcode(iban string, cents int64, ref string, dueDate time.Time) string {
+	ms := finnishIBAN.FindStringSubmatch(strings.Replace(iban, " ", "", -1))
+	if ms == nil || len(ref) > 20 {
+		return ""
+	}
+	if cents < 0 || cents > 99999999 {
+		cents = 0
+	}
+	return fmt.Sprintf("4%s%06d%02d000%020s%s", ms[1],
+		cents/100, cents%100, ref, dueDate.Format("060102"))
+}
+
+// drawBarcode draws a Code 128 barcode as filled rectangles.
+func drawBarcode(pdf *gofpdf.Fpdf, code string, x, y, w, h float64) {
+	bc, err := code128.Encode(code)
+	if err != nil {
+		return
+	}
+	modules := bc.Bounds().Dx()
+	moduleWidth := w / float64(modules)
+	pdf.SetFillColor(0, 0, 0)
+	for i := 0; i < modules; i++ {
+		if r, _, _, _ := bc.At(i, 0).RGBA(); r == 0 {
+			pdf.Rect(x+float64(i)*moduleWidth, y, moduleWidth, h, "F")
+		}
+	}
+}
+
+func formatIBAN(iban string) string {
+	var groups []string
+	for i := 0; i < len(iban); i += 4 {
+		end := i + 4
+		if end > len(iban) {
+			end = len(iban)
+		}
+		groups = append(groups, iban[i:end])
+	}
+	return strings.Join(groups, " ")
+}
+
+func InvoicePdf(m *model.Model, getWriter GetWriter, invoiceID string) error {
+	inv := m.GetInvoice(invoiceID)
+	if inv == nil {
+		return nil
+	}
+	settings := m.GetSettings()
+	pdf := newPdf()
+	pdf.SetFont(pdfFont, "", 10)
+	tr := norm.NFC.String
+	const sideMargin = 20
+	pdf.SetMargins(sideMargin, sideMargin, sideMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	pageWidth, _ := pdf.GetPageSize()
+	half := (pageWidth - 2*sideMargin) / 2
+	line := func(label, value string) {
+		pdf.SetX(sideMargin + half)
+		pdf.SetFont("", "", 10)
+		pdf.CellFormat(half/2, 6, tr(label), "", 0, "L", false, 0, "")
+		pdf.SetFont("", "B", 10)
+		pdf.CellFormat(half/2, 6, tr(value), "", 1, "L", false, 0, "")
+	}
+
+	pdf.SetFont("", "B", 12)
+	pdf.CellFormat(half, 8, tr(settings.OrgFullName), "", 0, "L", false, 0, "")
+	pdf.SetFont("", "B", 16)
+	pdf.CellFormat(half, 8, tr("LASKU"), "", 1, "L", false, 0, "")
+	line("Laskun numero", inv.InvoiceID)
+	line("Päiväys", inv.InvoiceDateFi)
+	line("Eräpäivä", inv.DueDateFi)
+	line("Viitenumero", inv.ReferenceNumber)
+
+	pdf.SetY(60)
+	pdf.SetFont("", "", 11)
+	pdf.MultiCell(half, 6, tr(inv.CustomerName+"\n"+inv.CustomerAddress),
+		"", "L", false)
+
+	pdf.SetY(100)
+	pdf.SetFont("", "B", 10)
+	pdf.CellFormat(3*half/2, 7, tr("Selite"), "B", 0, "L", false, 0, "")
+	pdf.CellFormat(half/2, 7, tr("Summa €"), "B", 1, "R", false, 0, "")
+	pdf.SetFont("", "", 10)
+	pdf.CellFormat(3*half/2, 7, tr(inv.Description), "", 0, "L", false, 0, "")
+	pdf.CellFormat(half/2, 7, tr(inv.Amount), "", 1, "R", false, 0, "")
+	pdf.SetFont("", "B", 10)
+	pdf.CellFormat(3*half/2, 7, tr("Yhteensä"), "T", 0, "L", false, 0, "")
+	pdf.CellFormat(half/2, 7, tr(inv.Amount), "T", 1, "R", false, 0, "")
+
+	// Simplified bank transfer form (tilisiirtolomake).
+	formY := 200.0
+	pdf.SetY(formY)
+	pdf.SetFont("", "", 9)
+	formLine := func(label, value string) {
+		pdf.SetX(sideMargin)
+		pdf.CellFormat(half/2, 7, tr(label), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(3*half/2, 7, tr(value), "B", 1, "L", false, 0, "")
+	}
+	formLine("Saajan tilinumero", formatIBAN(settings.OrgIBAN)+
+		"   BIC "+settings.OrgBIC)
+	formLine("Saaja", settings.OrgFullName)
+	formLine("Maksaja", inv.CustomerName)
+	formLine("Viitenumero", inv.ReferenceNumber)
+	formLine("Eräpäivä", inv.DueDateFi)
+	formLine("Euro", inv.Amount)
+	dueDate, _ := time.Parse("2006-01-02", inv.DueDateISO)
+	code := virtualBarcode(settings.OrgIBAN, inv.AmountCents,
+		inv.ReferenceNumber, dueDate)
+	if code != "" {
+		drawBarcode(pdf, code, sideMargin, formY+50, 105, 10)
+		pdf.SetXY(sideMargin, formY+61)
+		pdf.SetFont(pdfMonoFont, "", 8)
+		pdf.CellFormat(2*half, 5, code, "", 1, "L", false, 0, "")
+	}
+
+	writer, err := getWriter("application/pdf",
+		generateFilename(m,
+			model.ReportFilter{EndDateFi: inv.InvoiceDateFi},
+			"lasku-"+inv.InvoiceID)+".pdf")
+	if err != nil {
+		return err
+	}
+	return pdf.Output(writer)
+}
@@ -0,0 +1,32 @@
+package reports
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtualBarcode(t *testing.T) {
+	dueDate := time.Date(2010, 6, 12, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		iban  string
+		cents int64
+		ref   string
+		want  string
+	}{
+		{"FI79 4405 2020 0360 82", 488315, "868516259619897",
+			"479440520200360820048831500000000868516259619897100612"},
+		{"FI7944052020036082", 0, "1232",
+			"479440520200360820000000000000000000000000001232100612"},
+		{"FI7944052020036082", 100000000, "1232",
+			"479440520200360820000000000000000000000000001232100612"},
+		{"SE4550000000058398257466", 488315, "1232", ""},
+		{"FI7944052020036082", 488315, "123456789012345678901", ""},
+	}
+	for _, test := range tests {
+		got := virtualBarcode(test.iban, test.cents, test.ref, dueDate)
+		if got != test.want {
+			t.Errorf("virtualBarcode(%q, %d, %q) = %q, want %q",
+				test.iban, test.cents, test.ref, got, test.want)
+		}
+	}
+}
@@ -6,17 +6,16 @@ import (
 	"github.com/lassik/massikone/model"
 )
 
-func GeneralJournalPdf(m *model.Model, getWriter GetWriter) {
+func GeneralJournal(m *model.Model, getWriter GetWriter, opts Options) error {
 	const numberWidth = 2
 	const accountWidth = 8
 	const descriptionWidth = 10
 	acctMap := m.GetAccountMap()
 	doc := document{
-		title:     "Päiväkirja",
-		filename:  "päiväkirja",
-		orgName:   m.GetSettings().OrgShortName,
-		period:    "1.1.2018 - 31.12.2018",
-		printDate: "1.12.2018",
+		title:    "Päiväkirja",
+		filename: "päiväkirja",
+		orgName:  m.GetSettings().OrgShortName,
+		period:   m.ReportPeriod(opts.ReportFilter),
 		headerRow: []cell{
 			cell{text: "Nro", width: numberWidth},
 			cell{text: "Pvm/Tili", width: accountWidth},
@@ -25,12 +24,13 @@ func GeneralJournalPdf(m *model.Model, getWriter GetWriter) {
 			cell{text: "Selite", width: descriptionWidth},
 		},
 	}
-	journal := m.GetJournal()
+	journal := m.GetJournal(opts.ReportFilter)
 	for _, document := range journal.Documents {
 		doc.rows = append(doc.rows, []cell{
 			cell{
 				text:  document.DocumentID,
 				width: numberWidth,
+				href:  documentHref(document.DocumentID),
 			},
 			cell{
 				text: document.PaidDateFi,
@@ -93,5 +93,5 @@ func GeneralJournalPdf(m *model.Model, getWriter GetWriter) {
 		},
 		cell{width: descriptionWidth},
 	})
-	writePdf(m, doc, getWriter)
+	return writeDocument(m, doc, getWriter, opts)
 }
@@ -6,7 +6,7 @@ import (
 	"github.com/lassik/massikone/model"
 )
 
-func GeneralLedgerPdf(m *model.Model, getWriter GetWriter) {
+func GeneralLedger(m *model.Model, getWriter GetWriter, opts Options) error {
 	const dateWidth = 3
 	const numberWidth = 2
 	const accountWidth = 8
@@ -21,11 +21,10 @@ func GeneralLedgerPdf(m *model.Model, getWriter GetWriter) {
 		cell{width: descriptionWidth},
 	}
 	doc := document{
-		title:     "Pääkirja",
-		filename:  "pääkirja",
-		orgName:   m.GetSettings().OrgShortName,
-		period:    "1.1.2018 - 31.12.2018",
-		printDate: "1.12.2018",
+		title:    "Pääkirja",
+		filename: "pääkirja",
+		orgName:  m.GetSettings().OrgShortName,
+		period:   m.ReportPeriod(opts.ReportFilter),
 		headerRow: []cell{
 			cell{text: "Tili", width: numberWidth},
 			cell{text: "Tili/Tosite", width: dateWidth},
@@ -36,17 +35,20 @@ func GeneralLedgerPdf(m *model.Model, getWriter GetWriter) {
 			cell{text: "Selite", width: descriptionWidth},
 		},
 	}
-	ledger := m.GetLedger()
+	withProject(m, &doc, opts.ProjectID)
+	ledger := m.GetLedger(opts.ReportFilter)
 	for _, account := range ledger.Accounts {
 		doc.rows = append(doc.rows, []cell{
 			cell{
 				text:  strconv.Itoa(account.AccountID),
 				width: numberWidth,
+				href:  opts.accountHref(account.AccountID),
 			},
 			cell{
 				text: account.AccountTitle,
 				width: accountWidth + 2*numberWidth +
 					descriptionWidth,
+				href: opts.accountStatementHref(account.AccountID),
 			},
 		})
 		if account.StartingBalance != "" {
@@ -76,6 +78,7 @@ func GeneralLedgerPdf(m *model.Model, getWriter GetWriter) {
 				cell{
 					text:  entry.DocumentID,
 					width: numberWidth,
+					href:  documentHref(entry.DocumentID),
 				},
 				cell{
 					text:       entry.PaidDateFi,
@@ -124,5 +127,5 @@ func GeneralLedgerPdf(m *model.Model, getWriter GetWriter) {
 		cell{width: numberWidth},
 		cell{width: descriptionWidth},
 	})
-	writePdf(m, doc, getWriter)
+	return writeDocument(m, doc, getWriter, opts)
 }
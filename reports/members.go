@@ -0,0 +1,42 @@
+package reports
+
+import (
+	"encoding/csv"
+	"fmt"
+
+	"github.com/lassik/massikone/model"
+)
+
+// UnpaidMembersCsv lists the members who have not paid the membership
+// fee of the given period, for sending reminders.
+func UnpaidMembersCsv(m *model.Model, getWriter GetWriter, periodID int64) error {
+	period := m.GetPeriod(periodID)
+	if period == nil {
+		return nil
+	}
+	w, err := getWriter("text/csv; charset=utf-8",
+		fmt.Sprintf("maksamattomat-jasenmaksut-%s.csv",
+			period.StartDateISO))
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+	cw.Write([]string{"Jäsen", "Nimi", "Sähköposti", "Puhelin",
+		"Osoite", "Jäsenlaji", "Jäsenmaksu", "Maksettu", "Viite"})
+	for _, fee := range m.GetUnpaidMemberFees(period.PeriodID) {
+		cw.Write([]string{
+			fee.Member.MemberID,
+			fee.Member.FullName,
+			fee.Member.Email,
+			fee.Member.Phone,
+			fee.Member.Address,
+			fee.Member.MembershipType,
+			fee.Amount,
+			fee.Paid,
+			fee.ReferenceNumber,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
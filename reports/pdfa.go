@@ -0,0 +1,249 @@
+package reports
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// PDF/A parts that the PDF reports can conform to, both at level B.
+const (
+	PdfA1 = 1
+	PdfA2 = 2
+)
+
+// ParsePdfA parses the PDF/A part asked for: "" for plain PDF, or "1",
+// "1b", "2" or "2b".
+func ParsePdfA(s string) (int, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "1", "1b":
+		return PdfA1, nil
+	case "2", "2b":
+		return PdfA2, nil
+	}
+	return 0, fmt.Errorf("Unknown PDF/A part: %q", s)
+}
+
+// s15Fixed16 encodes a number the way ICC profiles store them.
+func s15Fixed16(x float64) uint32 {
+	return uint32(int32(x * 65536))
+}
+
+// srgbProfile builds a minimal ICC version 2 display profile for sRGB,
+// which PDF/A needs as the output intent of documents using RGB and
+// gray colours. The primaries are adapted to the D50 white point.
+func srgbProfile() []byte {
+	xyz := func(x, y, z float64) []byte {
+		b := []byte("XYZ \x00\x00\x00\x00")
+		for _, v := range []float64{x, y, z} {
+			b = binary.BigEndian.AppendUint32(b, s15Fixed16(v))
+		}
+		return b
+	}
+	desc := []byte("desc\x00\x00\x00\x00")
+	desc = binary.BigEndian.AppendUint32(desc, uint32(len("sRGB")+1))
+	desc = append(desc, "sRGB\x00"...)
+	desc = append(desc, make([]byte, 4+4+2+1+67)...)
+	// Gamma 2.2 as u8Fixed8Number.
+	trc := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01\x02\x33\x00\x00")
+	tags := []struct {
+		signature string
+		data      []byte
+	}{
+		{"desc", desc},
+		{"cprt", []byte("text\x00\x00\x00\x00Public domain\x00")},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+	offset := 128 + 4 + 12*len(tags)
+	var table, data []byte
+	table = binary.BigEndian.AppendUint32(table, uint32(len(tags)))
+	for _, tag := range tags {
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+		table = append(table, tag.signature...)
+		table = binary.BigEndian.AppendUint32(table,
+			uint32(offset+len(data)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag.data)))
+		data = append(data, tag.data...)
+	}
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(128+len(table)+len(data)))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000)
+	copy(header[12:], "mntrRGB XYZ ")
+	copy(header[36:], "acsp")
+	binary.BigEndian.PutUint32(header[68:], s15Fixed16(0.9642))
+	binary.BigEndian.PutUint32(header[72:], s15Fixed16(1.0))
+	binary.BigEndian.PutUint32(header[76:], s15Fixed16(0.8249))
+	return append(append(header, table...), data...)
+}
+
+// pdfAInfo is the document information that goes both into the info
+// dictionary and, as PDF/A requires, into the XMP metadata.
+type pdfAInfo struct {
+	title    string
+	author   string
+	subject  string
+	producer string
+	date     time.Time
+}
+
+func xmlText(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func pdfAMetadata(part int, info pdfAInfo) []byte {
+	date := info.date.Format("2006-01-02T15:04:05")
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n" +
+		`<x:xmpmeta xmlns:x="adobe:ns:meta/">` + "\n" +
+		`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
+	b.WriteString(`<rdf:Description rdf:about=""` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	if info.title != "" {
+		fmt.Fprintf(&b, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n",
+			xmlText(info.title))
+	}
+	if info.author != "" {
+		fmt.Fprintf(&b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n",
+			xmlText(info.author))
+	}
+	if info.subject != "" {
+		fmt.Fprintf(&b, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n",
+			xmlText(info.subject))
+	}
+	b.WriteString("</rdf:Description>\n")
+	fmt.Fprintf(&b, `<rdf:Description rdf:about=""`+
+		` xmlns:pdf="http://ns.adobe.com/pdf/1.3/">`+"\n"+
+		"<pdf:Producer>%s</pdf:Producer>\n"+
+		"</rdf:Description>\n", xmlText(info.producer))
+	fmt.Fprintf(&b, `<rdf:Description rdf:about=""`+
+		` xmlns:xmp="http://ns.adobe.com/xap/1.0/">`+"\n"+
+		"<xmp:CreateDate>%s</xmp:CreateDate>\n"+
+		"<xmp:ModifyDate>%s</xmp:ModifyDate>\n"+
+		"</rdf:Description>\n", date, date)
+	fmt.Fprintf(&b, `<rdf:Description rdf:about=""`+
+		` xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">`+"\n"+
+		"<pdfaid:part>%d</pdfaid:part>\n"+
+		"<pdfaid:conformance>B</pdfaid:conformance>\n"+
+		"</rdf:Description>\n", part)
+	b.WriteString("</rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+var (
+	pdfTrailerSize = regexp.MustCompile(`/Size (\d+)`)
+	pdfTrailerRoot = regexp.MustCompile(`/Root (\d+) 0 R`)
+	pdfTrailerInfo = regexp.MustCompile(`/Info (\d+) 0 R`)
+	pdfXrefEntry   = regexp.MustCompile(`(\d{10}) \d{5} [nf]`)
+	pdfNoFiles     = regexp.MustCompile(
+		`/Names <<\s*/EmbeddedFiles << /Names \[\s*\] >>\s*>>\s*`)
+)
+
+// convertToPdfA turns a document written by gofpdf into PDF/A. It
+// marks the file as binary, adds the XMP metadata and the sRGB output
+// intent to the catalog, drops the empty list of embedded files that
+// PDF/A-1 forbids, and rewrites the cross-reference table with a
+// document ID.
+func convertToPdfA(pdf []byte, part int, info pdfAInfo) ([]byte, error) {
+	xrefStart := bytes.LastIndex(pdf, []byte("\nxref\n")) + 1
+	trailerStart := bytes.LastIndex(pdf, []byte("\ntrailer\n"))
+	if xrefStart < 1 || trailerStart < xrefStart {
+		return nil, fmt.Errorf("PDF/A: cross-reference table not found")
+	}
+	trailer := pdf[trailerStart:]
+	sizeMatch := pdfTrailerSize.FindSubmatch(trailer)
+	rootMatch := pdfTrailerRoot.FindSubmatch(trailer)
+	infoMatch := pdfTrailerInfo.FindSubmatch(trailer)
+	if sizeMatch == nil || rootMatch == nil || infoMatch == nil {
+		return nil, fmt.Errorf("PDF/A: incomplete trailer")
+	}
+	size, _ := strconv.Atoi(string(sizeMatch[1]))
+	root, _ := strconv.Atoi(string(rootMatch[1]))
+	var offsets []int
+	for _, m := range pdfXrefEntry.FindAllSubmatch(
+		pdf[xrefStart:trailerStart], -1) {
+		offset, _ := strconv.Atoi(string(m[1]))
+		offsets = append(offsets, offset)
+	}
+	if len(offsets) != size {
+		return nil, fmt.Errorf("PDF/A: %d objects in a table of %d",
+			len(offsets), size)
+	}
+	headerEnd := bytes.IndexByte(pdf, '\n') + 1
+	const binaryMark = "%\xe2\xe3\xcf\xd3\n"
+	shift := len(binaryMark)
+
+	catalogStart := offsets[root]
+	catalogEnd := bytes.Index(pdf[catalogStart:xrefStart],
+		[]byte("endobj\n"))
+	if catalogEnd < 0 ||
+		catalogStart+catalogEnd+len("endobj\n") != xrefStart {
+		return nil, fmt.Errorf("PDF/A: catalog is not the last object")
+	}
+	catalog := pdf[catalogStart : catalogStart+catalogEnd]
+	typeCatalog := []byte("/Type /Catalog\n")
+	if !bytes.Contains(catalog, typeCatalog) {
+		return nil, fmt.Errorf("PDF/A: object %d is not the catalog", root)
+	}
+	iccObj, intentObj, metadataObj := size, size+1, size+2
+	catalog = pdfNoFiles.ReplaceAll(catalog, nil)
+	catalog = bytes.Replace(catalog, typeCatalog, []byte(fmt.Sprintf(
+		"%s/Metadata %d 0 R\n/OutputIntents [%d 0 R]\n",
+		typeCatalog, metadataObj, intentObj)), 1)
+
+	var out bytes.Buffer
+	out.Write(pdf[:headerEnd])
+	out.WriteString(binaryMark)
+	out.Write(pdf[headerEnd:catalogStart])
+	out.Write(catalog)
+	out.WriteString("endobj\n")
+	for i := 1; i < size; i++ {
+		offsets[i] += shift
+	}
+	newObj := func(dict string, stream []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n", len(offsets)-1)
+		if stream == nil {
+			fmt.Fprintf(&out, "%s\nendobj\n", dict)
+			return
+		}
+		fmt.Fprintf(&out, "<<%s /Length %d>>\nstream\n", dict, len(stream))
+		out.Write(stream)
+		out.WriteString("\nendstream\nendobj\n")
+	}
+	newObj("/N 3", srgbProfile())
+	newObj(fmt.Sprintf("<</Type /OutputIntent /S /GTS_PDFA1"+
+		" /OutputConditionIdentifier (sRGB IEC61966-2.1)"+
+		" /Info (sRGB IEC61966-2.1) /DestOutputProfile %d 0 R>>",
+		iccObj), nil)
+	newObj("/Type /Metadata /Subtype /XML",
+		pdfAMetadata(part, info))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n", len(offsets))
+	out.WriteString("0000000000 65535 f \n")
+	for _, offset := range offsets[1:] {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	id := fmt.Sprintf("%x", md5.Sum(pdf))
+	fmt.Fprintf(&out, "trailer\n<<\n/Size %d\n/Root %d 0 R\n/Info %s 0 R\n"+
+		"/ID [<%s> <%s>]\n>>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets), root, infoMatch[1], id, id, xref)
+	return out.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+package reports
+
+import (
+	"github.com/lassik/massikone/model"
+)
+
+// ProjectSummaryPdf lists the revenue, expenses and profit of each
+// project.
+func ProjectSummary(m *model.Model, getWriter GetWriter, opts Options) error {
+	const titleWidth = 8
+	const amountWidth = 2
+	doc := document{
+		title:    "Projektit",
+		filename: "projektit",
+		orgName:  m.GetSettings().OrgShortName,
+		period:   m.ReportPeriod(opts.ReportFilter),
+		headerRow: []cell{
+			cell{text: "Projekti", width: titleWidth},
+			cell{text: "Tuotot", width: amountWidth, rightAlign: true},
+			cell{text: "Kulut", width: amountWidth, rightAlign: true},
+			cell{text: "Tulos", width: amountWidth, rightAlign: true},
+		},
+	}
+	var revenueCents, expenseCents, profitCents int64
+	for _, s := range m.GetProjectSummary(opts.ReportFilter) {
+		revenueCents += s.RevenueCents
+		expenseCents += s.ExpenseCents
+		profitCents += s.ProfitCents
+		doc.rows = append(doc.rows, []cell{
+			cell{
+				text:  s.Project.Title,
+				width: titleWidth,
+				href:  projectHref(s.Project.ProjectID),
+			},
+			cell{text: s.Revenue, width: amountWidth, rightAlign: true},
+			cell{text: s.Expense, width: amountWidth, rightAlign: true},
+			cell{text: s.Profit, width: amountWidth, rightAlign: true},
+		})
+	}
+	doc.rows = append(doc.rows, []cell{
+		cell{text: "Yhteensä", bold: true, width: titleWidth},
+		cell{
+			text:       amountFromCents(revenueCents),
+			bold:       true,
+			width:      amountWidth,
+			rightAlign: true,
+		},
+		cell{
+			text:       amountFromCents(expenseCents),
+			bold:       true,
+			width:      amountWidth,
+			rightAlign: true,
+		},
+		cell{
+			text:       amountFromCents(profitCents),
+			bold:       true,
+			width:      amountWidth,
+			rightAlign: true,
+		},
+	})
+	return writeDocument(m, doc, getWriter, opts)
+}
+
+// projectHref links to the on-screen income statement of a project.
+func projectHref(projectID string) string {
+	if projectID == "" {
+		return ""
+	}
+	projectOpts := Options{
+		ReportFilter: model.ReportFilter{ProjectID: projectID},
+	}
+	return "/raportti/tuloslaskelma-erittelyin" + projectOpts.query(FormatHtml)
+}
@@ -0,0 +1,203 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/lassik/massikone/model"
+)
+
+// documentAccounts lists the debit or credit accounts of a document
+// with their titles, each account once.
+func documentAccounts(document model.Document, isDebit bool,
+	acctMap map[int]model.Account) string {
+	var accounts []string
+	seen := map[int]bool{}
+	for _, entry := range document.Entries {
+		if entry.IsDebit != isDebit || seen[entry.AccountID] {
+			continue
+		}
+		seen[entry.AccountID] = true
+		accounts = append(accounts, fmt.Sprintf("%d %s",
+			entry.AccountID, acctMap[entry.AccountID].Title))
+	}
+	return strings.Join(accounts, ", ")
+}
+
+// receiptsPerPdf is the most documents that the full statement puts in
+// one receipts file. The images of the whole file are held in memory
+// until it has been written.
+const receiptsPerPdf = 50
+
+// ReceiptsPdf prints every document of the filter on a page of its
+// own: first the number, date, description, amount and accounts of the
+// document, then its images scaled to fit the rest of the page. The
+// file is always PDF, written as PDF/A if the options ask for it.
+func ReceiptsPdf(m *model.Model, getWriter GetWriter, opts Options) error {
+	journal := m.GetJournal(opts.ReportFilter)
+	images, _ := m.GetDocumentsForImages(opts.ReportFilter)
+	if m.Err != nil {
+		return m.Err
+	}
+	return receiptsPdf(m, getWriter, opts, journal.Documents, images,
+		generateFilename(m, opts.ReportFilter, "tositteet"))
+}
+
+// receiptsPdfParts writes the receipts of the filter into files of at
+// most receiptsPerPdf documents each, named after their first and last
+// document, so that the images of only one file are in memory at a
+// time.
+func receiptsPdfParts(m *model.Model, getWriter GetWriter, opts Options) error {
+	journal := m.GetJournal(opts.ReportFilter)
+	images, _ := m.GetDocumentsForImages(opts.ReportFilter)
+	if m.Err != nil {
+		return m.Err
+	}
+	documents := journal.Documents
+	if len(documents) <= receiptsPerPdf {
+		return receiptsPdf(m, getWriter, opts, documents, images,
+			generateFilename(m, opts.ReportFilter, "tositteet"))
+	}
+	for len(documents) > 0 {
+		part := documents
+		if len(part) > receiptsPerPdf {
+			part = part[:receiptsPerPdf]
+		}
+		documents = documents[len(part):]
+		filename := generateFilename(m, opts.ReportFilter,
+			"tositteet "+part[0].DocumentID+"-"+part[len(part)-1].DocumentID)
+		if err := receiptsPdf(m, getWriter, opts, part, images,
+			filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receiptsPdf prints the documents as ReceiptsPdf describes, taking
+// their images from the list, which is in document order.
+func receiptsPdf(m *model.Model, getWriter GetWriter, opts Options,
+	documents []model.Document, images []model.DocumentImageFile,
+	filename string) error {
+	acctMap := m.GetAccountMap()
+	orgName := m.GetSettings().OrgShortName
+	if m.Err != nil {
+		return m.Err
+	}
+	pdf := newPdf()
+	pdf.SetFont(pdfFont, "", 9)
+	tr := norm.NFC.String
+	const topMargin = 25
+	const sideMargin = 10
+	const labelWidth = 30.0
+	const lineHeight = 6.0
+	pdf.SetMargins(sideMargin, topMargin, sideMargin)
+	pdf.SetAutoPageBreak(false, sideMargin)
+	pageWidth, pageHeight := pdf.GetPageSize()
+	pageWidth -= 2 * sideMargin
+	printDate := time.Now().Format("2.1.2006")
+	pdf.AliasNbPages("")
+	pdf.SetHeaderFunc(func() {
+		div3 := pageWidth / 3
+		const height = 8.0
+		pdf.SetY(sideMargin)
+		pdf.SetX(sideMargin)
+		pdf.SetFont("", "", 11)
+		pdf.CellFormat(div3, height, tr(orgName),
+			"", 0, "L", false, 0, "")
+		pdf.SetFont("", "B", 0)
+		pdf.CellFormat(div3, height, "Tositteet",
+			"", 0, "C", false, 0, "")
+		pdf.SetFont("", "", 0)
+		pdf.CellFormat(div3, height,
+			fmt.Sprintf("Sivu %d / {nb}", pdf.PageNo()),
+			"", 1, "R", false, 0, "")
+		pdf.SetX(sideMargin + 2*div3)
+		pdf.CellFormat(div3, height, printDate,
+			"", 1, "R", false, 0, "")
+	})
+	field := func(label, value string) {
+		pdf.SetX(sideMargin)
+		pdf.SetFont("", "B", 10)
+		pdf.CellFormat(labelWidth, lineHeight, label,
+			"", 0, "L", false, 0, "")
+		pdf.SetFont("", "", 10)
+		pdf.MultiCell(pageWidth-labelWidth, lineHeight, tr(value),
+			"", "L", false)
+	}
+	for _, document := range documents {
+		documentID, _ := strconv.Atoi(document.DocumentID)
+		var documentImages []model.DocumentImageFile
+		for len(images) > 0 && images[0].DocumentID <= documentID {
+			if images[0].DocumentID == documentID {
+				documentImages = append(documentImages, images[0])
+			}
+			images = images[1:]
+		}
+		pdf.AddPage()
+		pdf.SetFont("", "B", 14)
+		pdf.CellFormat(pageWidth, 10, "Tosite "+document.DocumentID,
+			"", 1, "L", false, 0, "")
+		field("Päivämäärä", document.PaidDateFi)
+		field("Selite", document.Description)
+		field("Summa", document.Amount)
+		field("Debet", documentAccounts(document, true, acctMap))
+		field("Kredit", documentAccounts(document, false, acctMap))
+		pdf.Ln(lineHeight)
+		if len(documentImages) == 0 {
+			pdf.SetFont("", "", 10)
+			pdf.CellFormat(pageWidth, lineHeight, "Tosite puuttuu.",
+				"", 1, "L", false, 0, "")
+			continue
+		}
+		// The images share the rest of the page one below another.
+		top := pdf.GetY()
+		slotHeight := (pageHeight - sideMargin - top) /
+			float64(len(documentImages))
+		for i, image := range documentImages {
+			imageData, _, err := m.GetImage(image.ImageID)
+			if err != nil {
+				return fmt.Errorf("Image %s of document %d: %v",
+					image.ImageID, image.DocumentID, err)
+			}
+			options := gofpdf.ImageOptions{
+				ImageType: strings.TrimPrefix(path.Ext(image.ImageID), "."),
+			}
+			info := pdf.RegisterImageOptionsReader(image.ImageID, options,
+				bytes.NewReader(imageData))
+			if !pdf.Ok() {
+				return fmt.Errorf("Image %s of document %d: %v",
+					image.ImageID, image.DocumentID, pdf.Error())
+			}
+			width, height := info.Width(), info.Height()
+			scale := pageWidth / width
+			if height*scale > slotHeight-2 {
+				scale = (slotHeight - 2) / height
+			}
+			pdf.ImageOptions(image.ImageID,
+				sideMargin, top+float64(i)*slotHeight,
+				width*scale, height*scale,
+				false, options, 0, "")
+		}
+	}
+	if len(documents) == 0 {
+		pdf.AddPage()
+	}
+	writer, err := getWriter("application/pdf", filename+".pdf")
+	if err != nil {
+		return err
+	}
+	return outputPdf(pdf, writer, opts.PdfA, pdfAInfo{
+		title:    "Tositteet",
+		author:   tr(orgName),
+		producer: "Massikone",
+		date:     time.Now(),
+	})
+}
@@ -0,0 +1,219 @@
+package reports
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lassik/massikone/model"
+)
+
+// Reimbursements lists how much each user is owed. Without dates it
+// covers everything still unpaid today, since old debts stay due.
+func Reimbursements(m *model.Model, getWriter GetWriter, opts Options) error {
+	const nameWidth = 6
+	const amountWidth = 2
+	const ibanWidth = 5
+	period := opts.ReportFilter.String()
+	if period == "" {
+		period = "Tilanne " + time.Now().Format("2.1.2006")
+	}
+	doc := document{
+		title:    "Kulukorvaukset",
+		filename: "kulukorvaukset",
+		orgName:  m.GetSettings().OrgShortName,
+		period:   period,
+		headerRow: []cell{
+			cell{text: "Saaja", width: nameWidth},
+			cell{text: "Tilinumero", width: ibanWidth},
+			cell{text: "Jätetty", width: amountWidth, rightAlign: true},
+			cell{text: "Hyväksytty", width: amountWidth, rightAlign: true},
+			cell{text: "Maksamatta", width: amountWidth, rightAlign: true},
+		},
+	}
+	var totalCents int64
+	for _, balance := range m.GetReimbursementBalances(opts.ReportFilter) {
+		totalCents += balance.SubmittedCents + balance.ApprovedCents
+		doc.rows = append(doc.rows, []cell{
+			cell{text: balance.User.FullName, width: nameWidth},
+			cell{text: balance.User.IBAN, width: ibanWidth},
+			cell{
+				text:       balance.Submitted,
+				width:      amountWidth,
+				rightAlign: true,
+			},
+			cell{
+				text:       balance.Approved,
+				width:      amountWidth,
+				rightAlign: true,
+			},
+			cell{
+				text:       balance.Outstanding,
+				width:      amountWidth,
+				rightAlign: true,
+			},
+		})
+	}
+	doc.rows = append(doc.rows, []cell{
+		cell{width: nameWidth},
+		cell{width: ibanWidth},
+		cell{width: amountWidth},
+		cell{width: amountWidth},
+		cell{
+			text:       amountFromCents(totalCents),
+			width:      amountWidth,
+			rightAlign: true,
+			bold:       true,
+		},
+	})
+	return writeDocument(m, doc, getWriter, opts)
+}
+
+// The subset of ISO 20022 pain.001.001.03 (SEPA credit transfer
+// initiation) that Finnish banks need for a simple payment batch.
+
+type sepaAccount struct {
+	IBAN string `xml:"Id>IBAN"`
+}
+
+type sepaTransaction struct {
+	EndToEndID string      `xml:"PmtId>EndToEndId"`
+	Amount     sepaAmount  `xml:"Amt>InstdAmt"`
+	Creditor   string      `xml:"Cdtr>Nm"`
+	Account    sepaAccount `xml:"CdtrAcct"`
+	Message    string      `xml:"RmtInf>Ustrd"`
+}
+
+type sepaAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type sepaPaymentInfo struct {
+	PaymentInfoID  string            `xml:"PmtInfId"`
+	PaymentMethod  string            `xml:"PmtMtd"`
+	NumberOfTxs    int               `xml:"NbOfTxs"`
+	ControlSum     string            `xml:"CtrlSum"`
+	ServiceLevel   string            `xml:"PmtTpInf>SvcLvl>Cd"`
+	ExecutionDate  string            `xml:"ReqdExctnDt"`
+	Debtor         string            `xml:"Dbtr>Nm"`
+	DebtorAccount  sepaAccount       `xml:"DbtrAcct"`
+	DebtorAgentBIC string            `xml:"DbtrAgt>FinInstnId>BIC"`
+	ChargeBearer   string            `xml:"ChrgBr"`
+	Transactions   []sepaTransaction `xml:"CdtTrfTxInf"`
+}
+
+type sepaDocument struct {
+	XMLName     xml.Name        `xml:"urn:iso:std:iso:20022:tech:xsd:pain.001.001.03 Document"`
+	MessageID   string          `xml:"CstmrCdtTrfInitn>GrpHdr>MsgId"`
+	CreatedTime string          `xml:"CstmrCdtTrfInitn>GrpHdr>CreDtTm"`
+	NumberOfTxs int             `xml:"CstmrCdtTrfInitn>GrpHdr>NbOfTxs"`
+	ControlSum  string          `xml:"CstmrCdtTrfInitn>GrpHdr>CtrlSum"`
+	Initiator   string          `xml:"CstmrCdtTrfInitn>GrpHdr>InitgPty>Nm"`
+	PaymentInfo sepaPaymentInfo `xml:"CstmrCdtTrfInitn>PmtInf"`
+}
+
+func sepaAmountFromCents(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+// ReimbursementsSepaXml writes the approved reimbursements as a SEPA
+// payment batch that can be uploaded to the online bank. The batch
+// can be made again until its reimbursements are marked paid with
+// PayReimbursements. Nothing is written if the association or any
+// payee lacks bank details, or if a document is closed and so could
+// not be marked paid.
+func ReimbursementsSepaXml(m *model.Model, getWriter GetWriter) error {
+	settings := m.GetSettings()
+	reimbursements := m.GetApprovedReimbursements()
+	if m.Err != nil {
+		return m.Err
+	}
+	var problems []string
+	if settings.OrgIBAN == "" {
+		problems = append(problems, "yhdistyksen tilinumero puuttuu")
+	}
+	if settings.OrgBIC == "" {
+		problems = append(problems, "yhdistyksen pankin BIC puuttuu")
+	}
+	if len(reimbursements) == 0 {
+		problems = append(problems, "ei hyväksyttyjä kulukorvauksia")
+	}
+	var noIBAN []string
+	var closed []string
+	for _, document := range reimbursements {
+		if document.PaidUser.IBAN == "" {
+			noIBAN = append(noIBAN, fmt.Sprintf("%s (tosite #%s)",
+				document.PaidUser.FullName, document.DocumentID))
+		}
+		if document.IsClosed {
+			closed = append(closed, "#"+document.DocumentID)
+		}
+	}
+	if len(noIBAN) > 0 {
+		problems = append(problems, "saajalta puuttuu tilinumero: "+
+			strings.Join(noIBAN, ", "))
+	}
+	if len(closed) > 0 {
+		problems = append(problems, "tosite on suljettu: "+
+			strings.Join(closed, ", "))
+	}
+	if len(problems) > 0 {
+		return UserError("SEPA-maksuaineistoa ei voi tehdä: " +
+			strings.Join(problems, "; "))
+	}
+	now := time.Now()
+	messageID := "MASSIKONE-" + now.Format("20060102150405")
+	var totalCents int64
+	var transactions []sepaTransaction
+	for _, document := range reimbursements {
+		totalCents += document.AmountCents
+		transactions = append(transactions, sepaTransaction{
+			EndToEndID: messageID + "-" + document.DocumentID,
+			Amount: sepaAmount{
+				Currency: "EUR",
+				Value:    sepaAmountFromCents(document.AmountCents),
+			},
+			Creditor: shorten(document.PaidUser.FullName),
+			Account:  sepaAccount{IBAN: document.PaidUser.IBAN},
+			// Unstructured remittance information is at most 140
+			// characters.
+			Message: shortenTo(fmt.Sprintf("Kulukorvaus, tosite %s: %s",
+				document.DocumentID, document.Description), 140),
+		})
+	}
+	doc := sepaDocument{
+		MessageID:   messageID,
+		CreatedTime: now.Format("2006-01-02T15:04:05"),
+		NumberOfTxs: len(transactions),
+		ControlSum:  sepaAmountFromCents(totalCents),
+		Initiator:   settings.OrgFullName,
+		PaymentInfo: sepaPaymentInfo{
+			PaymentInfoID:  messageID,
+			PaymentMethod:  "TRF",
+			NumberOfTxs:    len(transactions),
+			ControlSum:     sepaAmountFromCents(totalCents),
+			ServiceLevel:   "SEPA",
+			ExecutionDate:  now.Format("2006-01-02"),
+			Debtor:         settings.OrgFullName,
+			DebtorAccount:  sepaAccount{IBAN: settings.OrgIBAN},
+			DebtorAgentBIC: settings.OrgBIC,
+			ChargeBearer:   "SLEV",
+			Transactions:   transactions,
+		},
+	}
+	w, err := getWriter("application/xml",
+		generateFilename(m,
+			model.ReportFilter{EndDateFi: now.Format("2.1.2006")},
+			"kulukorvaukset-sepa")+".xml")
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
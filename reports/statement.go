@@ -4,18 +4,102 @@ import (
 	"github.com/lassik/massikone/model"
 )
 
-func IncomeStatementPdf(m *model.Model, getWriter GetWriter) {
-	blankPdf(m, getWriter, "tuloslaskelma")
+func statementDocument(m *model.Model, title string,
+	statement model.Statement, opts Options, detailed bool) document {
+	const titleWidth = 8
+	const amountWidth = 2
+	doc := document{
+		title:    title,
+		filename: title,
+		orgName:  m.GetSettings().OrgShortName,
+		period:   statement.Period,
+		headerRow: []cell{
+			cell{width: titleWidth},
+			cell{
+				text:       statement.Period,
+				width:      amountWidth,
+				rightAlign: true,
+			},
+			cell{
+				text:       statement.PreviousPeriod,
+				width:      amountWidth,
+				rightAlign: true,
+			},
+		},
+	}
+	if detailed {
+		doc.title += " erittelyin"
+		doc.filename += " erittelyin"
+	}
+	headingLevel := 0
+	for _, row := range statement.Rows {
+		isAccount := !row.IsHeading() && !row.IsTotal
+		if !detailed && isAccount {
+			continue
+		}
+		title := row.Title
+		indentLevel := 0
+		href := ""
+		if row.IsHeading() {
+			headingLevel = row.NestingLevel
+			indentLevel = headingLevel
+		} else if isAccount {
+			title = row.AccountIDStr + " " + title
+			indentLevel = headingLevel + 1
+			href = opts.accountHref(row.AccountID)
+		}
+		bold := !isAccount
+		doc.rows = append(doc.rows, []cell{
+			cell{
+				text:        title,
+				bold:        bold,
+				indentLevel: indentLevel,
+				width:       titleWidth,
+				href:        href,
+			},
+			cell{
+				text:       row.Balance,
+				bold:       bold,
+				rightAlign: true,
+				width:      amountWidth,
+			},
+			cell{
+				text:       row.PreviousBalance,
+				bold:       bold,
+				rightAlign: true,
+				width:      amountWidth,
+			},
+		})
+	}
+	return doc
 }
 
-func IncomeStatementDetailedPdf(m *model.Model, getWriter GetWriter) {
-	blankPdf(m, getWriter, "tuloslaskelma erittelyin")
+func incomeStatement(m *model.Model, getWriter GetWriter, opts Options,
+	detailed bool) error {
+	doc := statementDocument(m, "Tuloslaskelma",
+		m.GetIncomeStatement(opts.ReportFilter), opts, detailed)
+	withProject(m, &doc, opts.ProjectID)
+	return writeDocument(m, doc, getWriter, opts)
 }
 
-func BalanceSheetPdf(m *model.Model, getWriter GetWriter) {
-	blankPdf(m, getWriter, "tase")
+func IncomeStatement(m *model.Model, getWriter GetWriter, opts Options) error {
+	return incomeStatement(m, getWriter, opts, false)
 }
 
-func BalanceSheetDetailedPdf(m *model.Model, getWriter GetWriter) {
-	blankPdf(m, getWriter, "tase erittelyin")
+func IncomeStatementDetailed(m *model.Model, getWriter GetWriter, opts Options) error {
+	return incomeStatement(m, getWriter, opts, true)
+}
+
+func balanceSheet(m *model.Model, getWriter GetWriter, opts Options,
+	detailed bool) error {
+	doc := statementDocument(m, "Tase", m.GetBalanceSheet(opts.ReportFilter), opts, detailed)
+	return writeDocument(m, doc, getWriter, opts)
+}
+
+func BalanceSheet(m *model.Model, getWriter GetWriter, opts Options) error {
+	return balanceSheet(m, getWriter, opts, false)
+}
+
+func BalanceSheetDetailed(m *model.Model, getWriter GetWriter, opts Options) error {
+	return balanceSheet(m, getWriter, opts, true)
 }
@@ -0,0 +1,73 @@
+package reports
+
+import (
+	"strconv"
+
+	"github.com/lassik/massikone/model"
+)
+
+// TrialBalance lists the opening balance, debits, credits and closing
+// balance of each account, and checks that debits equal credits.
+func TrialBalance(m *model.Model, getWriter GetWriter, opts Options) error {
+	const numberWidth = 2
+	const titleWidth = 7
+	const amountWidth = 3
+	amount := func(text string, bold bool) cell {
+		return cell{
+			text:       text,
+			bold:       bold,
+			width:      amountWidth,
+			rightAlign: true,
+		}
+	}
+	doc := document{
+		title:    "Saldoluettelo",
+		filename: "saldoluettelo",
+		orgName:  m.GetSettings().OrgShortName,
+		headerRow: []cell{
+			cell{text: "Tili", width: numberWidth},
+			cell{text: "Nimi", width: titleWidth},
+			cell{text: "Alkusaldo", width: amountWidth, rightAlign: true},
+			cell{text: "Debet", width: amountWidth, rightAlign: true},
+			cell{text: "Kredit", width: amountWidth, rightAlign: true},
+			cell{text: "Loppusaldo", width: amountWidth, rightAlign: true},
+		},
+	}
+	tb := m.GetTrialBalance(opts.ReportFilter)
+	doc.period = tb.Period
+	for _, row := range tb.Rows {
+		doc.rows = append(doc.rows, []cell{
+			cell{
+				text:  strconv.Itoa(row.AccountID),
+				width: numberWidth,
+				href:  opts.accountStatementHref(row.AccountID),
+			},
+			cell{text: row.Title, width: titleWidth},
+			amount(row.OpeningBalance, false),
+			amount(row.Debit, false),
+			amount(row.Credit, false),
+			amount(row.ClosingBalance, false),
+		})
+	}
+	doc.rows = append(doc.rows, []cell{
+		cell{width: numberWidth},
+		cell{text: "Yhteensä", bold: true, width: titleWidth},
+		cell{width: amountWidth},
+		amount(tb.TotalDebit, true),
+		amount(tb.TotalCredit, true),
+		cell{width: amountWidth},
+	})
+	check := "Debet ja kredit täsmäävät."
+	if !tb.IsBalanced {
+		check = "Debet ja kredit eivät täsmää, erotus " + tb.Difference + "."
+	}
+	doc.rows = append(doc.rows, []cell{
+		cell{width: numberWidth},
+		cell{
+			text:  check,
+			bold:  !tb.IsBalanced,
+			width: titleWidth + 4*amountWidth,
+		},
+	})
+	return writeDocument(m, doc, getWriter, opts)
+}
@@ -1,11 +1,12 @@
 package reports
 
 import (
+	"bytes"
 	"fmt"
 	"io"
-	"log"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/jung-kurt/gofpdf"
 	"golang.org/x/text/unicode/norm"
@@ -21,16 +22,23 @@ type cell struct {
 	rightAlign  bool
 	width       int
 	indentLevel int
+	href        string
 }
 
 type document struct {
-	title     string
-	filename  string
-	orgName   string
-	period    string
-	printDate string
+	title    string
+	filename string
+	orgName  string
+	period   string
+	// The filter of the report, for naming the file.
+	filter    model.ReportFilter
 	headerRow []cell
 	rows      [][]cell
+	// Print the running totals of the debit and credit columns at
+	// the bottom of each page and carry them to the top of the next.
+	carryForward bool
+	// Write PDF/A of this part instead of plain PDF if nonzero.
+	pdfA int
 }
 
 type pdfCtx struct {
@@ -40,12 +48,6 @@ type pdfCtx struct {
 	tr         func(s string) string
 }
 
-func check(err error) {
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
 var whitespace = regexp.MustCompile(`\s+`)
 var nonWordChar = regexp.MustCompile(`[^\d\pL-]`)
 var dashesMany = regexp.MustCompile(`--+`)
@@ -64,10 +66,16 @@ func truncateUnicode(str string, maxRunes int) string {
 }
 
 func shorten(str string) string {
+	return shortenTo(str, 50)
+}
+
+// shortenTo keeps the first line of the string with its whitespace
+// collapsed, cut to maxRunes.
+func shortenTo(str string, maxRunes int) string {
 	str = strings.SplitN(str, "\n", 2)[0]
 	str = whitespace.ReplaceAllString(str, " ")
 	str = strings.TrimSpace(str)
-	str = truncateUnicode(str, 50)
+	str = truncateUnicode(str, maxRunes)
 	return str
 }
 
@@ -97,20 +105,46 @@ func amountFromCents(cents int64) string {
 	return fmt.Sprintf("%s%d,%02d", sign, euros, cents)
 }
 
-func generateFilename(m *model.Model, document string) string {
-	year := "2018" // TODO
+// generateFilename names a file after the organization, the year that
+// the filter covers and the document.
+func generateFilename(m *model.Model, filter model.ReportFilter,
+	document string) string {
+	year := m.ReportYear(filter)
 	settings := m.GetSettings()
 	return norm.NFC.String(slug(
 		settings.OrgShortName + "-" + year + "-" + document))
 }
 
-func blankPdf(m *model.Model, getWriter GetWriter, filename string) {
+// withProject narrows the title and filename of a report that only
+// covers one project.
+func withProject(m *model.Model, doc *document, projectID string) {
+	if projectID == "" {
+		return
+	}
+	if project := m.GetProject(projectID); project != nil {
+		doc.title += ": " + project.Title
+		doc.filename += " " + project.Title
+	}
+}
+
+// The PDF reports embed DejaVu fonts, generated into fonts.go from
+// reports/fonts, so that any Unicode text can be printed.
+const (
+	pdfFont     = "DejaVuSans"
+	pdfMonoFont = "DejaVuSansMono"
+)
+
+// newPdf starts an A4 document with the embedded fonts.
+func newPdf() *gofpdf.Fpdf {
 	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
-	writer, err := getWriter("application/pdf",
-		generateFilename(m, filename)+".pdf")
-	check(err)
-	check(pdf.Output(writer))
+	addFont := func(family, style, filename string) {
+		pdf.AddUTF8FontFromBytes(family, style,
+			[]byte(fonts["/"+filename].Contents))
+	}
+	addFont(pdfFont, "", "DejaVuSans.ttf")
+	addFont(pdfFont, "B", "DejaVuSans-Bold.ttf")
+	addFont(pdfMonoFont, "", "DejaVuSansMono.ttf")
+	return pdf
 }
 
 func doRow(ctx pdfCtx, row []cell, isHeader bool) {
@@ -153,19 +187,70 @@ func doRow(ctx pdfCtx, row []cell, isHeader bool) {
 	pdf.Ln(-1)
 }
 
-func writePdf(m *model.Model, doc document, getWriter GetWriter) {
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.SetFont("Helvetica", "", 9)
-	trFromUtf8 := pdf.UnicodeTranslatorFromDescriptor("")
-	tr := func(s string) string {
-		return trFromUtf8(norm.NFC.String(s))
+// carryColumns returns the indexes of the debit and credit columns of
+// the document.
+func carryColumns(doc document) []int {
+	var columns []int
+	for i, c := range doc.headerRow {
+		if c.text == "Debet" || c.text == "Kredit" {
+			columns = append(columns, i)
+		}
+	}
+	return columns
+}
+
+// isCarriedRow tells whether the amounts on a row are part of the
+// carried totals. Bold rows are totals of the rows above them.
+func isCarriedRow(doc document, row []cell) bool {
+	if len(row) != len(doc.headerRow) {
+		return false
+	}
+	for _, c := range row {
+		if c.bold {
+			return false
+		}
 	}
+	return true
+}
+
+func writePdf(m *model.Model, doc document, getWriter GetWriter) error {
+	pdf := newPdf()
+	pdf.SetFont(pdfFont, "", 9)
+	tr := norm.NFC.String
 	const topMargin = 25
 	const sideMargin = 10
 	pdf.SetMargins(sideMargin, topMargin, sideMargin)
 	pageWidth, _ := pdf.GetPageSize()
 	pageWidth -= 2 * sideMargin
 	ctx := pdfCtx{pdf: pdf, pageWidth: pageWidth, sideMargin: sideMargin, tr: tr}
+	printDate := time.Now().Format("2.1.2006")
+	pdf.AliasNbPages("")
+	var columns []int
+	if doc.carryForward {
+		columns = carryColumns(doc)
+	}
+	carried := make([]int64, len(columns))
+	finished := false
+	carryRow := func() []cell {
+		row := make([]cell, len(doc.headerRow))
+		for i, c := range doc.headerRow {
+			row[i] = cell{width: c.width, rightAlign: c.rightAlign}
+		}
+		row[0].text = "Siirto"
+		for i, column := range columns {
+			row[column].text = amountFromCents(carried[i])
+		}
+		return row
+	}
+	if len(columns) > 0 {
+		pdf.SetFooterFunc(func() {
+			if finished {
+				return
+			}
+			pdf.SetY(-20)
+			doRow(ctx, carryRow(), true)
+		})
+	}
 	pdf.SetHeaderFunc(func() {
 		div3 := pageWidth / 3
 		const height = 8.0
@@ -181,21 +266,73 @@ func writePdf(m *model.Model, doc document, getWriter GetWriter) {
 			"", 0, "C", false, 0, "")
 		pdf.SetFont("", "", 0)
 		pdf.CellFormat(div3, height,
-			tr(fmt.Sprintf("Sivu %d", pdf.PageNo())),
+			tr(fmt.Sprintf("Sivu %d / {nb}", pdf.PageNo())),
 			"", 1, "R", false, 0, "")
 		pdf.SetX(sideMargin + div3)
 		pdf.CellFormat(div3, height, tr(doc.period),
 			"", 0, "C", false, 0, "")
-		pdf.CellFormat(div3, height, tr(doc.printDate),
+		pdf.CellFormat(div3, height, tr(printDate),
 			"", 1, "R", false, 0, "")
 		doRow(ctx, doc.headerRow, true)
+		if len(columns) > 0 && pdf.PageNo() > 1 {
+			doRow(ctx, carryRow(), true)
+		}
 	})
 	pdf.AddPage()
 	for _, thisRow := range doc.rows {
 		doRow(ctx, thisRow, false)
+		if isCarriedRow(doc, thisRow) {
+			for i, column := range columns {
+				// The amounts were formatted by amountFromCents,
+				// which leaves zero empty.
+				cents, _ := model.SignedCentsFromAmount(thisRow[column].text)
+				carried[i] += cents
+			}
+		}
 	}
+	finished = true
 	writer, err := getWriter("application/pdf",
-		generateFilename(m, doc.filename)+".pdf")
-	check(err)
-	check(pdf.Output(writer))
+		generateFilename(m, doc.filter, doc.filename)+".pdf")
+	if err != nil {
+		return err
+	}
+	return outputPdf(pdf, writer, doc.pdfA, pdfAInfo{
+		title:    tr(doc.title),
+		author:   tr(doc.orgName),
+		subject:  tr(doc.period),
+		producer: "Massikone",
+		date:     time.Now(),
+	})
+}
+
+// outputPdf writes the finished document, converting it to PDF/A of
+// the given part if that is nonzero.
+func outputPdf(pdf *gofpdf.Fpdf, writer io.Writer, part int, info pdfAInfo) error {
+	if part == 0 {
+		return pdf.Output(writer)
+	}
+	// Empty fields would still be written, and must then be left out
+	// of the metadata as well.
+	if info.title != "" {
+		pdf.SetTitle(info.title, true)
+	}
+	if info.author != "" {
+		pdf.SetAuthor(info.author, true)
+	}
+	if info.subject != "" {
+		pdf.SetSubject(info.subject, true)
+	}
+	pdf.SetProducer(info.producer, true)
+	pdf.SetCreationDate(info.date)
+	pdf.SetModificationDate(info.date)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return err
+	}
+	pdfA, err := convertToPdfA(buf.Bytes(), part, info)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(pdfA)
+	return err
 }
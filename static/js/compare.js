@@ -54,7 +54,87 @@ $(function() {
     });
   }
 
+  var statusTexts = {
+    unmatched: "Ei vastaavaa viitettä",
+    duplicate: "Kirjattu jo aiemmin",
+    paid: "Maksettu",
+    partial: "Osamaksu",
+    overpaid: "Ylisuoritus"
+  };
+
+  var statusClasses = {
+    unmatched: "",
+    duplicate: "info",
+    paid: "success",
+    partial: "warning",
+    overpaid: "danger"
+  };
+
+  function referenceFromEntry(entry) {
+    var ref = (entry.referenceNumber || "").replace(/\s+/g, "");
+    if (ref) {
+      return ref;
+    }
+    var g = (entry.message || "").match(/viite:?\s*(\d[\d ]{2,})/i);
+    return g ? g[1].replace(/\s+/g, "") : "";
+  }
+
+  function showPayments(results) {
+    $("#payments > tbody").empty();
+    results.forEach(function(result) {
+      $("#payments > tbody").append(
+        $("<tr>")
+          .addClass(statusClasses[result.Status])
+          .append($("<td>").addClass("text-right").text(result.DateFi))
+          .append($("<td>").addClass("text-right").text(formatCents(result.Cents)))
+          .append($("<td>").text(result.ReferenceNumber))
+          .append($("<td>").text(statusTexts[result.Status]))
+          .append(
+            $("<td>").append(
+              result.DocumentID
+                ? $("<a>")
+                    .attr("href", "/tosite/" + result.DocumentID)
+                    .text("#" + result.DocumentID)
+                : ""
+            )
+          )
+      );
+    });
+  }
+
+  function initPayments(entries) {
+    var payments = [];
+    entries.forEach(function(entry) {
+      var ref = referenceFromEntry(entry);
+      if (ref && entry.amount && entry.amount.cents > 0 && entry.date) {
+        payments.push({
+          ArchivalID: entry.archivalId || "",
+          DateFi: entry.date.finnish,
+          Cents: entry.amount.cents,
+          ReferenceNumber: ref,
+          Message: entry.message || ""
+        });
+      }
+    });
+    $("#payments-button")
+      .prop("disabled", payments.length === 0)
+      .off("click")
+      .click(function() {
+        $.ajax({
+          url: "/api/payments",
+          method: "POST",
+          contentType: "application/json",
+          data: JSON.stringify(payments)
+        })
+          .done(showPayments)
+          .fail(function(jqXHR) {
+            alert("Error: " + jqXHR.statusText);
+          });
+      });
+  }
+
   function initCompare(entries) {
+    initPayments(entries);
     $.get({
       url: "/api/compare"
     })
@@ -9,4 +9,8 @@ func main() {
 		WriteFile("templates.go")
 	packer.Package("model").Map("migrations", "model/migrations").
 		WriteFile("model/migrations.go")
+	packer.Package("reports").Map("fonts", "reports/fonts").
+		WriteFile("reports/fonts.go")
+	packer.Package("reports").Map("templates", "templates").
+		WriteFile("reports/templates.go")
 }